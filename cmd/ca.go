@@ -0,0 +1,35 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// caCmd represents the ca command
+var caCmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Manage the cluster SSH certificate authority",
+	Long: `Manage the cluster SSH certificate authority
+
+The certificate authority is enabled by "cluster.certificateAuthority.enabled" in
+the cluster configuration file.
+`,
+}
+
+func init() {
+	rootCmd.AddCommand(caCmd)
+}
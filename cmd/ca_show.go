@@ -0,0 +1,46 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+var caShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the public key of the cluster SSH certificate authority",
+	RunE:  caShow,
+}
+
+func init() {
+	caCmd.AddCommand(caShowCmd)
+}
+
+func caShow(cmd *cobra.Command, args []string) error {
+	cluster, err := cluster.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	key, err := cluster.CAPublicKey()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(key)
+	return err
+}
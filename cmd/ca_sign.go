@@ -0,0 +1,104 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+var caSignCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Sign a user certificate with the cluster SSH certificate authority",
+	Long: `Sign a user certificate with the cluster SSH certificate authority
+
+The certificate grants access to the machines as the given principals, i.e. users,
+without touching their authorized keys. It's written next to the public key, where
+ssh looks for it. For example:
+
+vind ca sign --principal ubuntu --ttl 8h --key ~/.ssh/id_ed25519.pub
+ssh -p <PORT> ubuntu@localhost
+`,
+	RunE: caSign,
+}
+
+var caSignOptions struct {
+	principals []string
+	ttl        time.Duration
+	key        string
+	output     string
+}
+
+func init() {
+	caSignCmd.Flags().StringSliceVar(&caSignOptions.principals, "principal", nil, "Users the certificate is valid for")
+	caSignCmd.Flags().DurationVar(&caSignOptions.ttl, "ttl", 8*time.Hour, "Validity of the certificate")
+	caSignCmd.Flags().StringVarP(&caSignOptions.key, "key", "k", "~/.ssh/id_ed25519.pub", "Public key file to sign")
+	caSignCmd.Flags().StringVarP(&caSignOptions.output, "output", "o", "", "Certificate file, or - for stdout. Defaults to the key file with a -cert.pub suffix")
+	caSignCmd.MarkFlagRequired("principal")
+	caCmd.AddCommand(caSignCmd)
+}
+
+func caSign(cmd *cobra.Command, args []string) error {
+	opts := &caSignOptions
+	if opts.ttl <= 0 {
+		return errors.New("ttl must be positive")
+	}
+
+	c, err := cluster.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	keyPath, err := homedir.Expand(opts.key)
+	if err != nil {
+		return err
+	}
+	publicKey, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("can't read public key file: %w", err)
+	}
+
+	cert, err := c.SignUserKey(publicKey, opts.principals, opts.ttl)
+	if err != nil {
+		return err
+	}
+	data := gossh.MarshalAuthorizedKey(cert)
+
+	output := opts.output
+	if output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if output == "" {
+		output = strings.TrimSuffix(keyPath, ".pub") + "-cert.pub"
+	}
+	if c.DryRun() {
+		fmt.Printf("Dry run: certificate not written to %s\n", output)
+		return nil
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Certificate written to %s, valid until %s\n", output, time.Unix(int64(cert.ValidBefore), 0).Format(time.RFC3339))
+	return nil
+}
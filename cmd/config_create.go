@@ -69,6 +69,9 @@ func init() {
 	private := &defaultConfig.Cluster.PrivateKey
 	configCreateCmd.PersistentFlags().StringVarP(private, "key", "k", *private, "Name of the private and public key files")
 
+	keyType := &defaultConfig.Cluster.KeyType
+	configCreateCmd.PersistentFlags().StringVar(keyType, "key-type", *keyType, "Type of the generated SSH key pair: {ed25519,rsa,ecdsa}")
+
 	machineSetName := &defaultConfig.MachineSets[0].Name
 	configCreateCmd.PersistentFlags().StringVarP(machineSetName, "machineset", "s", *machineSetName, "Name of the MachineSet")
 
@@ -17,7 +17,6 @@ package cmd
 
 import (
 	"errors"
-	"fmt"
 	"strings"
 
 	c "github.com/brightzheng100/vind/pkg/cluster"
@@ -28,18 +27,31 @@ import (
 var cpCmd = &cobra.Command{
 	Use:     "cp",
 	Aliases: []string{"copy"},
-	Short:   "Copy files or folders between a machine and the host file system",
+	Short:   "Copy files or folders between machines and the host file system",
 	Long: `
-cp <MACHINE_NAME:SRC_PATH> <HOST_DEST_PATH>
-cp <HOST_SRC_PATH> <MACHINE_NAME:DEST_PATH>
+cp <MACHINE_NAME:SRC_PATH> <HOST_DEST_PATH|->
+cp <HOST_SRC_PATH|-> <MACHINE_NAME:DEST_PATH>
+cp <MACHINE_NAME:SRC_PATH> <MACHINE_NAME:DEST_PATH>
 
-Copy files or folders between a machine and the host file system
+Copy files or folders between machines and the host file system
+
+The destination machine name can be a pattern, like 'workers-*', to copy to all
+the matching machines in parallel. "-" streams a tar archive from stdin, extracted
+into the DEST_PATH directory, or to stdout, like "docker cp".
 `,
+	Example: `  vind cp test-node0:/etc/hosts test-node1:/tmp/hosts
+  vind cp ./bin 'workers-*:/usr/local/bin' --chmod 755
+  tar -c ./conf | vind cp - test-node0:/etc --chown root:root`,
 	Args: validateCpArgs,
 	RunE: copy,
 }
 
+var cpOptions c.CopyOptions
+
 func init() {
+	cpCmd.Flags().BoolVarP(&cpOptions.Archive, "archive", "a", false, "Archive mode, keeping the uid/gid of the copied files")
+	cpCmd.Flags().StringVar(&cpOptions.Owner, "chown", "", "Set the owner, like user[:group], of the files copied into machines")
+	cpCmd.Flags().StringVar(&cpOptions.Mode, "chmod", "", "Set the mode, like 755 or u+x, of the files copied into machines")
 	rootCmd.AddCommand(cpCmd)
 }
 
@@ -48,44 +60,47 @@ func copy(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	// from: machine to host
-	// to: host to machine
-	copyFrom := strings.Contains(args[0], ":")
-	copyTo := strings.Contains(args[1], ":")
+	// from: machine to host, or machine
+	// to: host, or machine, to machines
+	fromMachine, srcPath, copyFrom := splitMachinePath(args[0])
+	toMachines, destPath, copyTo := splitMachinePath(args[1])
 
-	if copyFrom && copyTo || !copyFrom && !copyTo {
-		return errors.New("either copy from or to machine is supported")
+	if args[0] == c.Stdio && args[1] == c.Stdio {
+		return errors.New("src and dest can't be both stdio")
+	}
+	if !copyFrom && !copyTo {
+		return errors.New("either src or dest must be in a machine")
 	}
 
-	var machineName, srcPath, destPath string
-
-	if copyFrom { // copy from machine, like: cp machine:/root/ .
-		src := strings.Split(args[0], ":")
-		machineName = src[0]
-		srcPath = src[1]
-
-		destPath = args[1]
-
-		machine, err := cluster.GetMachineByMachineName(machineName)
-		if err != nil {
-			return fmt.Errorf("machine name not found: %s", machineName)
+	var from *c.Machine
+	if copyFrom {
+		if from, err = cluster.GetMachineByMachineName(fromMachine); err != nil {
+			return err
 		}
+	}
+	if !copyTo { // copy from machine, like: cp machine:/root/ .
+		return cluster.CopyFrom(cmd.Context(), from, srcPath, destPath, cpOptions)
+	}
 
-		return cluster.CopyFrom(machine, srcPath, destPath)
-	} else { // copy to machine, like: cp ./file machine:/root/
-		srcPath := args[0]
-
-		dest := strings.Split(args[1], ":")
-		machineName = dest[0]
-		destPath = dest[1]
-
-		machine, err := cluster.GetMachineByMachineName(machineName)
-		if err != nil {
-			return fmt.Errorf("machine name not found: %s", machineName)
-		}
+	to, err := cluster.GetMachinesByPattern(toMachines)
+	if err != nil {
+		return err
+	}
+	if copyFrom { // copy between machines, like: cp machine0:/root/file 'machine*:/root/'
+		return cluster.Copy(cmd.Context(), from, srcPath, to, destPath, cpOptions)
+	}
+	// copy to machines, like: cp ./file machine:/root/
+	return cluster.CopyTo(cmd.Context(), srcPath, to, destPath, cpOptions)
+}
 
-		return cluster.CopyTo(srcPath, machine, destPath)
+// splitMachinePath splits an arg like "MACHINE_NAME:PATH", returning whether
+// it's a machine path at all.
+func splitMachinePath(arg string) (machine string, path string, ok bool) {
+	machine, path, ok = strings.Cut(arg, ":")
+	if !ok {
+		return "", arg, false
 	}
+	return machine, path, true
 }
 
 func validateCpArgs(cmd *cobra.Command, args []string) error {
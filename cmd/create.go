@@ -25,17 +25,34 @@ import (
 var createCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a cluster",
-	RunE:  create,
+	Long: `Create a cluster
+
+The machines which already exist are left as is. With "--atomic", the machines
+and the keys created are removed if the creation fails or is interrupted, and
+a summary of what was rolled back is given.
+`,
+	RunE: create,
+}
+
+var createOptions struct {
+	atomic bool
 }
 
 func init() {
+	createCmd.Flags().BoolVar(&createOptions.atomic, "atomic", false, "Remove what was created if the creation fails")
 	rootCmd.AddCommand(createCmd)
 }
 
 func create(cmd *cobra.Command, args []string) error {
-	cluster, err := cluster.NewFromFile(configFile(cfgFile.config))
+	c, err := cluster.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	unlock, err := lockCluster(cmd, c)
 	if err != nil {
 		return err
 	}
-	return cluster.Create()
+	defer unlock()
+	err = c.Create(cmd.Context(), cluster.CreateOptions{Atomic: createOptions.atomic})
+	return reportInterrupted(cmd.Context(), c, err)
 }
@@ -37,5 +37,10 @@ func delete(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	return cluster.Delete()
+	unlock, err := lockCluster(cmd, cluster)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return reportInterrupted(cmd.Context(), cluster, cluster.Delete(cmd.Context()))
 }
@@ -0,0 +1,80 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Stream the events of the machines",
+	Long: `Stream the events of the machines, until interrupted
+
+The events of the containers of the cluster are normalized into the events of
+the machines: created, started, stopped, died with the exit code, oom,
+health_changed with the health status, and removed. They are written one per
+line, as text or as JSON objects.
+`,
+	Args: cobra.NoArgs,
+	RunE: streamEvents,
+}
+
+var eventsOptions struct {
+	machines []string
+	output   string
+}
+
+func init() {
+	eventsCmd.Flags().StringSliceVar(&eventsOptions.machines, "machines", nil, "Only stream the events of the machines, like test-node0,test-node1")
+	eventsCmd.Flags().StringVarP(&eventsOptions.output, "output", "o", "text", "Output formatting options: {text,json}.")
+	rootCmd.AddCommand(eventsCmd)
+}
+
+func streamEvents(cmd *cobra.Command, args []string) error {
+	c, err := cluster.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	var write func(cluster.MachineEvent) error
+	switch eventsOptions.output {
+	case "text":
+		write = func(event cluster.MachineEvent) error {
+			_, err := fmt.Fprintln(os.Stdout, event)
+			return err
+		}
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		write = func(event cluster.MachineEvent) error {
+			return encoder.Encode(event)
+		}
+	default:
+		return fmt.Errorf("unknown formatter '%s'", eventsOptions.output)
+	}
+
+	events, errs := c.Events(cmd.Context(), eventsOptions.machines)
+	for event := range events {
+		if err := write(event); err != nil {
+			return err
+		}
+	}
+	return <-errs
+}
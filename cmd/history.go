@@ -0,0 +1,125 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the operations which changed the cluster",
+	Long: `List the operations which changed the cluster
+
+The commands changing the cluster, like "create", "delete", "start", "stop" or
+"netem", are recorded with who ran them, when, the machines they were about,
+how they ended and how long they took, into the history of the cluster,
+~/.vind/clusters/<cluster>/history.jsonl. Dry runs aren't recorded.
+`,
+	Args: cobra.NoArgs,
+	RunE: history,
+}
+
+var historyOptions struct {
+	output  string
+	command string
+	user    string
+	machine string
+	result  string
+	since   string
+	last    int
+}
+
+func init() {
+	historyCmd.Flags().StringVarP(&historyOptions.output, "output", "o", "table", "Output formatting options: {table,json}.")
+	historyCmd.Flags().StringVar(&historyOptions.command, "command", "", "Only list the operations of the command, like create or \"keys rotate\"")
+	historyCmd.Flags().StringVar(&historyOptions.user, "user", "", "Only list the operations of the user")
+	historyCmd.Flags().StringVar(&historyOptions.machine, "machine", "", "Only list the operations about the machine")
+	historyCmd.Flags().StringVar(&historyOptions.result, "result", "", "Only list the operations which ended so: {succeeded,failed,interrupted}")
+	historyCmd.Flags().StringVar(&historyOptions.since, "since", "", "Only list the operations since a time, like 2025-01-06T10:00:00Z, or a duration ago, like 24h")
+	historyCmd.Flags().IntVarP(&historyOptions.last, "last", "n", 0, "Only list the last operations, 0 for all")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func history(cmd *cobra.Command, args []string) error {
+	c, err := cluster.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	filter := cluster.HistoryFilter{
+		Command: historyOptions.command,
+		User:    historyOptions.user,
+		Machine: historyOptions.machine,
+		Result:  historyOptions.result,
+		Last:    historyOptions.last,
+	}
+	if historyOptions.since != "" {
+		if filter.Since, err = parseSince(historyOptions.since); err != nil {
+			return err
+		}
+	}
+	entries, err := c.History(filter)
+	if err != nil {
+		return err
+	}
+
+	switch historyOptions.output {
+	case "json":
+		if entries == nil {
+			entries = []cluster.HistoryEntry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, string(data))
+		return err
+	case "table":
+		if len(entries) == 0 {
+			fmt.Println("No operation recorded")
+			return nil
+		}
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(table, "TIME\tUSER\tCOMMAND\tMACHINES\tRESULT\tDURATION")
+		for _, entry := range entries {
+			duration := time.Duration(entry.Duration * float64(time.Second)).Round(time.Millisecond)
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", entry.Time.Format(time.RFC3339), entry.User, entry.Command, strings.Join(entry.Machines, ","), entry.Result, duration)
+		}
+		return table.Flush()
+	default:
+		return fmt.Errorf("unknown formatter '%s'", historyOptions.output)
+	}
+}
+
+// parseSince parses a time, or a duration before now.
+func parseSince(since string) (time.Time, error) {
+	if d, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: should be a time, like 2025-01-06T10:00:00Z, or a duration, like 24h", since)
+	}
+	return t, nil
+}
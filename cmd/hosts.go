@@ -0,0 +1,37 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// hostsCmd represents the hosts command
+var hostsCmd = &cobra.Command{
+	Use:   "hosts",
+	Short: "Manage the host names of the machines",
+	Long: `Manage the host names of the machines
+
+Each machine resolves as <machine>.<cluster>.<domain> and as <machine> from all the
+machines, through a block of their /etc/hosts which vind keeps up to date when
+machines are created, started or stopped. The domain is "cluster.domain" in the
+cluster configuration file, "vind" by default.
+`,
+}
+
+func init() {
+	rootCmd.AddCommand(hostsCmd)
+}
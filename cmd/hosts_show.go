@@ -0,0 +1,48 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+var hostsShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the hosts entries of the running machines, as seen from the host",
+	RunE:  hostsShow,
+}
+
+func init() {
+	hostsCmd.AddCommand(hostsShowCmd)
+}
+
+func hostsShow(cmd *cobra.Command, args []string) error {
+	cluster, err := cluster.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	entries, err := cluster.HostEntries()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		fmt.Println(entry)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+var hostsSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Update the hosts files of the machines, and optionally of the host",
+	Long: `Update the hosts files of the machines, and optionally of the host
+
+With --host, the machines are also added to a block of the hosts file of the
+host, which usually needs sudo:
+
+  sudo vind hosts sync --host
+`,
+	RunE: hostsSync,
+}
+
+var hostsSyncOptions struct {
+	host      bool
+	hostsFile string
+}
+
+func init() {
+	hostsSyncCmd.Flags().BoolVar(&hostsSyncOptions.host, "host", false, "Also update the hosts file of the host")
+	hostsSyncCmd.Flags().StringVar(&hostsSyncOptions.hostsFile, "hosts-file", cluster.DefaultHostsFile(), "Hosts file of the host updated with --host")
+	hostsCmd.AddCommand(hostsSyncCmd)
+}
+
+func hostsSync(cmd *cobra.Command, args []string) error {
+	cluster, err := cluster.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	unlock, err := lockCluster(cmd, cluster)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := cluster.SyncHosts(cmd.Context()); err != nil {
+		return err
+	}
+	if !hostsSyncOptions.host {
+		return nil
+	}
+	return cluster.SyncHostsFile(hostsSyncOptions.hostsFile)
+}
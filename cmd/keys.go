@@ -0,0 +1,37 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// keysCmd represents the keys command
+var keysCmd = &cobra.Command{
+	Use:     "keys",
+	Aliases: []string{"key"},
+	Short:   "Manage the cluster SSH keys and the public key store",
+	Long: `Manage the cluster SSH keys and the public key store
+
+The public key store keeps named public keys in ~/.vind/keys, or $VIND_HOME/keys,
+which machines refer to by their "publicKey", "publicKeys" and "userPublicKeys"
+fields.
+`,
+}
+
+func init() {
+	rootCmd.AddCommand(keysCmd)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"strings"
+
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+var keysAddCmd = &cobra.Command{
+	Use:   "add <NAME> <PUBLIC_KEY>",
+	Short: "Add a public key to the key store",
+	Long: `Add a public key to the key store
+
+For example:
+
+vind keys add alice "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI... alice@example.com"
+`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: keysAdd,
+}
+
+func init() {
+	keysCmd.AddCommand(keysAddCmd)
+}
+
+func keysAdd(cmd *cobra.Command, args []string) error {
+	store := cluster.DefaultKeyStore()
+	if err := store.Init(); err != nil {
+		return err
+	}
+	return store.Store(args[0], strings.Join(args[1:], " ")+"\n")
+}
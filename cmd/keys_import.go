@@ -0,0 +1,67 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var keysImportCmd = &cobra.Command{
+	Use:   "import <PUBLIC_KEY_FILE> [NAME]",
+	Short: "Import a public key file into the key store",
+	Long: `Import a public key file into the key store
+
+The key is named after the file, without its ".pub" extension, unless a name is given.
+For example, below command imports the key as "id_ed25519":
+
+vind keys import ~/.ssh/id_ed25519.pub
+`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: keysImport,
+}
+
+func init() {
+	keysCmd.AddCommand(keysImportCmd)
+}
+
+func keysImport(cmd *cobra.Command, args []string) error {
+	path, err := homedir.Expand(args[0])
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "can't read public key file")
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), ".pub")
+	if len(args) > 1 {
+		name = args[1]
+	}
+
+	store := cluster.DefaultKeyStore()
+	if err := store.Init(); err != nil {
+		return err
+	}
+	return store.Store(name, string(data))
+}
@@ -0,0 +1,43 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+var keysRemoveCmd = &cobra.Command{
+	Use:     "remove <NAME1> [NAME2] [...]",
+	Aliases: []string{"rm"},
+	Short:   "Remove public keys from the key store",
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    keysRemove,
+}
+
+func init() {
+	keysCmd.AddCommand(keysRemoveCmd)
+}
+
+func keysRemove(cmd *cobra.Command, args []string) error {
+	store := cluster.DefaultKeyStore()
+	for _, name := range args {
+		if err := store.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+var keysRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replace the cluster SSH key pair and update all running machines",
+	Long: `Replace the cluster SSH key pair and update all running machines
+
+A new key pair of the configured type is generated and replaces the authorized
+keys of every running machine. The previous key pair is kept with a ".old" suffix.
+Stopped machines get the new key when they start, and the rotation can't run
+again until they do.
+`,
+	RunE: keysRotate,
+}
+
+func init() {
+	keysCmd.AddCommand(keysRotateCmd)
+}
+
+func keysRotate(cmd *cobra.Command, args []string) error {
+	cluster, err := cluster.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	unlock, err := lockCluster(cmd, cluster)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return cluster.RotateSSHKey(cmd.Context())
+}
@@ -0,0 +1,62 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+var keysShowCmd = &cobra.Command{
+	Use:   "show [MACHINE_NAME1 [MACHINE_NAME2] [...]]",
+	Short: "Show the fingerprints of the cluster key and of the keys authorized on the machines",
+	RunE:  keysShow,
+}
+
+func init() {
+	keysCmd.AddCommand(keysShowCmd)
+}
+
+func keysShow(cmd *cobra.Command, args []string) error {
+	c, err := cluster.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+
+	path, key, err := c.ClusterKey()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Cluster key: %s\n", path)
+	fmt.Printf("%s %s %s\n\n", key.Fingerprint, key.Comment, key.Type)
+
+	machines, err := c.AuthorizedKeys(cmd.Context(), args)
+	if err != nil {
+		return err
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(table, "MACHINE NAME\tUSER\tTYPE\tFINGERPRINT\tCOMMENT")
+	for _, m := range machines {
+		for _, k := range m.Keys {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", m.MachineName, m.User, k.Type, k.Fingerprint, k.Comment)
+		}
+	}
+	return table.Flush()
+}
@@ -0,0 +1,55 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+var keysSyncCmd = &cobra.Command{
+	Use:   "sync [MACHINE_NAME1 [MACHINE_NAME2] [...]]",
+	Short: "Repair the authorized keys of all running machines or specific machine(s) by given name(s)",
+	Long: `Repair the authorized keys of all running machines or specific machine(s) by given name(s)
+
+The configured public keys are installed for every machine user, without duplicates,
+and the permissions of the users' SSH directories are fixed. Keys authorized by other
+means are kept, unless --prune is specified.
+`,
+	RunE: keysSync,
+}
+
+var keysSyncOptions struct {
+	prune bool
+}
+
+func init() {
+	keysSyncCmd.Flags().BoolVar(&keysSyncOptions.prune, "prune", false, "Remove the authorized keys which are not configured")
+	keysCmd.AddCommand(keysSyncCmd)
+}
+
+func keysSync(cmd *cobra.Command, args []string) error {
+	cluster, err := cluster.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	unlock, err := lockCluster(cmd, cluster)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return cluster.SyncKeys(cmd.Context(), args, keysSyncOptions.prune)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	c "github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+// lbCmd represents the lb command
+var lbCmd = &cobra.Command{
+	Use:     "lb [NAME...]",
+	Aliases: []string{"load-balance"},
+	Short:   "Run the load balancers in front of the MachineSets",
+	Long: `Run the load balancers in front of the MachineSets
+
+The load balancers configured in the "loadBalancers" section of the cluster
+configuration file, or the named ones, balance the TCP connections to their host
+port over the running machines of their MachineSet, until interrupted. The
+machines are looked up continuously, so the backends follow the replicas.
+`,
+	RunE: loadBalance,
+}
+
+var lbOptions struct {
+	via string
+}
+
+func init() {
+	lbCmd.Flags().StringVar(&lbOptions.via, "via", c.ForwardViaAuto, "How to reach the machines: {auto,ip,exec}")
+	rootCmd.AddCommand(lbCmd)
+}
+
+func loadBalance(cmd *cobra.Command, args []string) error {
+	cluster, err := c.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	return cluster.LoadBalance(cmd.Context(), args, lbOptions.via)
+}
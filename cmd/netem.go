@@ -0,0 +1,79 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	c "github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+// netemCmd represents the netem command
+var netemCmd = &cobra.Command{
+	Use:   "netem <MACHINE|MACHINESET|PATTERN>...",
+	Short: "Impair the network traffic sent by machines",
+	Long: `Impair the network traffic sent by machines
+
+The traffic sent by the machines, to the machines given by "--to" only if any, is
+delayed, dropped, duplicated, corrupted or rate limited by the netem queueing
+discipline of tc, until reset by "vind netem reset". The faults are recorded in
+the cluster state, and applied again when the machines start.
+`,
+	Example: `  vind netem test-node0 --delay 100ms --jitter 10ms
+  vind netem test --loss 5% --to test-node2
+  vind netem list
+  vind netem reset`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: netem,
+}
+
+var netemOptions struct {
+	netem c.Netem
+	to    []string
+}
+
+func init() {
+	netemCmd.Flags().StringVar(&netemOptions.netem.Delay, "delay", "", "Delay of the packets, like 100ms")
+	netemCmd.Flags().StringVar(&netemOptions.netem.Jitter, "jitter", "", "Jitter of the delay, like 10ms")
+	netemCmd.Flags().StringVar(&netemOptions.netem.Loss, "loss", "", "Percentage of dropped packets, like 5%")
+	netemCmd.Flags().StringVar(&netemOptions.netem.Duplicate, "duplicate", "", "Percentage of duplicated packets")
+	netemCmd.Flags().StringVar(&netemOptions.netem.Corrupt, "corrupt", "", "Percentage of corrupted packets")
+	netemCmd.Flags().StringVar(&netemOptions.netem.Rate, "rate", "", "Bandwidth limit, like 1mbit")
+	netemCmd.Flags().StringSliceVar(&netemOptions.to, "to", nil, "Impair the traffic to these machines, machineSets or patterns only")
+	rootCmd.AddCommand(netemCmd)
+}
+
+func netem(cmd *cobra.Command, args []string) error {
+	cluster, err := c.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	unlock, err := lockCluster(cmd, cluster)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	machines, err := cluster.GetMachines(args)
+	if err != nil {
+		return err
+	}
+	var to []*c.Machine
+	if len(netemOptions.to) > 0 {
+		if to, err = cluster.GetMachines(netemOptions.to); err != nil {
+			return err
+		}
+	}
+	return cluster.Netem(cmd.Context(), machines, netemOptions.netem, to)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+var netemListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the active network faults and partitions",
+	Args:    cobra.NoArgs,
+	RunE:    netemList,
+}
+
+func init() {
+	netemCmd.AddCommand(netemListCmd)
+}
+
+func netemList(cmd *cobra.Command, args []string) error {
+	cluster, err := cluster.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	state, err := cluster.State()
+	if err != nil {
+		return err
+	}
+	if len(state.NetworkFaults) == 0 && len(state.Partitions) == 0 {
+		fmt.Println("No network fault is active")
+		return nil
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(table, "FAULT\tMACHINES\tRULE\tSINCE")
+	for _, fault := range state.NetworkFaults {
+		rule := fault.Netem.String()
+		if len(fault.To) > 0 {
+			rule += " to " + strings.Join(fault.To, ",")
+		}
+		fmt.Fprintf(table, "netem\t%s\t%s\t%s\n", fault.MachineName, rule, fault.Created.Format(time.RFC3339))
+	}
+	for _, partition := range state.Partitions {
+		fmt.Fprintf(table, "partition\t%s\tdrop between groups\t%s\n", partition, partition.Created.Format(time.RFC3339))
+	}
+	return table.Flush()
+}
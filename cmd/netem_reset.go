@@ -0,0 +1,57 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	c "github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+var netemResetCmd = &cobra.Command{
+	Use:   "reset [MACHINE|MACHINESET|PATTERN]...",
+	Short: "Clear the network faults and partitions, of the given machines only if any",
+	RunE:  netemReset,
+}
+
+func init() {
+	netemCmd.AddCommand(netemResetCmd)
+}
+
+func netemReset(cmd *cobra.Command, args []string) error {
+	cluster, err := c.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	unlock, err := lockCluster(cmd, cluster)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	var machines []*c.Machine
+	if len(args) > 0 {
+		if machines, err = cluster.GetMachines(args); err != nil {
+			return err
+		}
+	}
+	removed, err := cluster.ResetFaults(cmd.Context(), machines)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Cleared %d network fault(s)\n", removed)
+	return nil
+}
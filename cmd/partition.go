@@ -0,0 +1,88 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"strings"
+
+	c "github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+// partitionCmd represents the partition command
+var partitionCmd = &cobra.Command{
+	Use:   "partition <GROUP> | <GROUP>...",
+	Short: "Drop the network traffic between groups of machines",
+	Long: `Drop the network traffic between groups of machines
+
+A group is a comma separated list of machines, machineSets or patterns, and groups
+are separated by "|", or given as separate arguments. The traffic between machines
+of different groups is dropped by iptables, until reset by "vind netem reset".
+`,
+	Example: `  vind partition 'test-node0,test-node1 | test-node2'
+  vind partition test-node0,test-node1 test-node2`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: partition,
+}
+
+func init() {
+	rootCmd.AddCommand(partitionCmd)
+}
+
+func partition(cmd *cobra.Command, args []string) error {
+	cluster, err := c.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	unlock, err := lockCluster(cmd, cluster)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	var groups [][]*c.Machine
+	for _, group := range partitionGroups(args) {
+		machines, err := cluster.GetMachines(group)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, machines)
+	}
+	if len(groups) < 2 {
+		return errors.New("at least two groups of machines are needed, like 'node0,node1 | node2'")
+	}
+	return cluster.Partition(cmd.Context(), groups)
+}
+
+// partitionGroups splits the arguments into groups of names, separated by
+// "|" if any, or else one group per argument.
+func partitionGroups(args []string) [][]string {
+	joined := strings.Join(args, " ")
+	var specs []string
+	if strings.Contains(joined, "|") {
+		specs = strings.Split(joined, "|")
+	} else {
+		specs = args
+	}
+	var groups [][]string
+	for _, spec := range specs {
+		names := strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == ' ' })
+		if len(names) > 0 {
+			groups = append(groups, names)
+		}
+	}
+	return groups
+}
@@ -0,0 +1,176 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	c "github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/brightzheng100/vind/pkg/proxy"
+	"github.com/spf13/cobra"
+)
+
+// portForwardCmd represents the port-forward command
+var portForwardCmd = &cobra.Command{
+	Use:     "port-forward <MACHINE_NAME> <[HOST_IP:][HOST_PORT:]CONTAINER_PORT[/PROTOCOL]>...",
+	Aliases: []string{"pf"},
+	Short:   "Forward host ports to a machine, without recreating it",
+	Long: `Forward host ports to a machine, without recreating it
+
+The ports are forwarded by vind itself, until it's interrupted, or stopped by
+"--stop" if it runs in the background. The host port is the container port if
+omitted, or any free port if empty, like in ":80".
+
+The machine is reached by its IP on Linux, and through docker exec and nc, for TCP
+only, elsewhere. Use "--via" to choose.
+`,
+	Example: `  vind port-forward test-node0 8080:80
+  vind port-forward test-node0 :80 5353:53/udp --background
+  vind port-forward --stop test-node0`,
+	Args: validatePortForwardArgs,
+	RunE: portForward,
+}
+
+var portForwardOptions struct {
+	via        string
+	address    string
+	background bool
+	stop       bool
+	log        string
+}
+
+func init() {
+	portForwardCmd.Flags().StringVar(&portForwardOptions.via, "via", c.ForwardViaAuto, "How to reach the machine: {auto,ip,exec}")
+	portForwardCmd.Flags().StringVar(&portForwardOptions.address, "address", "127.0.0.1", "Host address to listen to, when not given in the ports")
+	portForwardCmd.Flags().BoolVarP(&portForwardOptions.background, "background", "d", false, "Run in the background, until stopped by --stop")
+	portForwardCmd.Flags().BoolVar(&portForwardOptions.stop, "stop", false, "Stop the port forwards running in the background, of the machine if given")
+	// set for the process running in the background, to record its log file
+	portForwardCmd.Flags().StringVar(&portForwardOptions.log, "log", "", "Log file of the port forward running in the background")
+	portForwardCmd.Flags().MarkHidden("log")
+	rootCmd.AddCommand(portForwardCmd)
+}
+
+func portForward(cmd *cobra.Command, args []string) error {
+	config, err := filepath.Abs(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	cluster, err := c.NewFromFile(config)
+	if err != nil {
+		return err
+	}
+
+	if portForwardOptions.stop {
+		machineName := ""
+		if len(args) > 0 {
+			machineName = args[0]
+		}
+		stopped, err := cluster.StopPortForwards(machineName)
+		if err == nil && stopped == 0 {
+			fmt.Println("No port forward is running")
+		}
+		return err
+	}
+
+	machine, err := cluster.GetMachineByMachineName(args[0])
+	if err != nil {
+		return err
+	}
+	var forwards []proxy.Forward
+	for _, spec := range args[1:] {
+		f, err := proxy.ParseForward(spec)
+		if err != nil {
+			return err
+		}
+		if f.HostIP == "" {
+			f.HostIP = portForwardOptions.address
+		}
+		forwards = append(forwards, f)
+	}
+
+	// a dry run has nothing to run in the background
+	if portForwardOptions.background && !cluster.DryRun() {
+		return portForwardInBackground(cluster.Dir(), config, args)
+	}
+
+	return cluster.PortForward(cmd.Context(), machine, forwards, c.PortForwardOptions{
+		Via: portForwardOptions.via,
+		Log: portForwardOptions.log,
+	})
+}
+
+// portForwardInBackground runs the same port forward in a detached process,
+// and waits for it to be recorded in the cluster state.
+func portForwardInBackground(dir string, config string, args []string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	log := filepath.Join(dir, "port-forward-"+args[0]+".log")
+	childArgs := append([]string{"port-forward"}, args...)
+	childArgs = append(childArgs, "-c", config, "--via", portForwardOptions.via, "--address", portForwardOptions.address, "--log", log)
+	pid, err := exec.StartDetached(log, self, childArgs...)
+	if err != nil {
+		return err
+	}
+
+	for deadline := time.Now().Add(15 * time.Second); time.Now().Before(deadline); {
+		time.Sleep(200 * time.Millisecond)
+		cluster, err := c.NewFromFile(config)
+		if err != nil {
+			return err
+		}
+		state, err := cluster.State()
+		if err != nil {
+			return err
+		}
+		for _, pf := range state.PortForwards {
+			if pf.PID == pid {
+				for _, f := range pf.Forwards {
+					fmt.Printf("Forwarding from %s -> %d/%s\n", f.HostAddress(), f.ContainerPort, f.Protocol)
+				}
+				fmt.Printf("Running in the background with pid %d, logging to %s\n", pid, log)
+				return nil
+			}
+		}
+		if !exec.ProcessAlive(pid) {
+			return fmt.Errorf("port forward failed, see %s", log)
+		}
+	}
+	return fmt.Errorf("port forward with pid %d isn't ready yet, see %s", pid, log)
+}
+
+func validatePortForwardArgs(cmd *cobra.Command, args []string) error {
+	stop, _ := cmd.Flags().GetBool("stop")
+	if stop {
+		if len(args) > 1 {
+			return errors.New("only a machine name can be given with --stop")
+		}
+		return nil
+	}
+	if len(args) < 2 {
+		return errors.New("a machine name and at least a port must be provided")
+	}
+	return nil
+}
@@ -17,30 +17,242 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"os/user"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/brightzheng100/vind/pkg/docker"
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/brightzheng100/vind/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
+// reportTimeout bounds the inspection of the machines reported after an
+// interruption.
+const reportTimeout = 30 * time.Second
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "vind",
-	Short: "A tool to create containers that look and work like virtual machines, on Docker.",
+	Use:                "vind",
+	Short:              "A tool to create containers that look and work like virtual machines, on Docker.",
+	PersistentPreRunE:  setUp,
+	PersistentPostRunE: emitScript,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The first interrupt cancels the running command, a second one kills vind.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		utils.Logger.Warnf("Received %s, cancelling... Interrupt again to quit right away", sig)
+		cancel()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	recordHistory(ctx, err)
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// reportInterrupted logs the state each machine was left in when the command
+// is interrupted, or times out, before returning its error.
+func reportInterrupted(ctx context.Context, c *cluster.Cluster, err error) error {
+	if err == nil || (ctx.Err() == nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)) {
+		return err
+	}
+	reportCtx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+	statuses, serr := c.Status(reportCtx, nil)
+	if serr != nil {
+		utils.Logger.Warnf("Can't report the state of the machines: %v", serr)
+		return err
+	}
+	utils.Logger.Warnf("Interrupted, the machines of cluster %s were left as follows:", c.Name())
+	for _, s := range statuses {
+		utils.Logger.Warnf("  %s: %s", s.MachineName, s.State)
+	}
+	return err
+}
+
 var cfgFile struct {
 	config string
 }
 
+var lockOptions struct {
+	wait time.Duration
+}
+
+// clusterOperation is the change of a cluster by a command.
+type clusterOperation struct {
+	cluster *cluster.Cluster
+	cmd     *cobra.Command
+	start   time.Time
+}
+
+// operation is the operation of the running command, recorded into the
+// history of its cluster once done.
+var operation *clusterOperation
+
+// lockCluster takes the lock of the cluster for the commands changing it,
+// waiting for it up to --wait-lock, and returns the function releasing it.
+// The command is then recorded into the history of the cluster, unless it's
+// a dry run.
+func lockCluster(cmd *cobra.Command, c *cluster.Cluster) (func(), error) {
+	unlock, err := c.Lock(cmd.Context(), lockOptions.wait)
+	if err != nil {
+		return nil, err
+	}
+	if !exec.DryRun() {
+		operation = &clusterOperation{cluster: c, cmd: cmd, start: time.Now()}
+	}
+	return unlock, nil
+}
+
+// recordHistory records the operation of the command, if any, into the
+// history of its cluster, with how it ended.
+func recordHistory(ctx context.Context, err error) {
+	if operation == nil {
+		return
+	}
+	entry := cluster.HistoryEntry{
+		Time:     operation.start,
+		User:     currentUser(),
+		Command:  strings.TrimPrefix(operation.cmd.CommandPath(), rootCmd.Name()+" "),
+		Args:     os.Args[1:],
+		Machines: affectedMachines(operation.cmd, operation.cluster),
+		Result:   cluster.HistorySucceeded,
+		Duration: time.Since(operation.start).Round(time.Millisecond).Seconds(),
+	}
+	if err != nil {
+		entry.Result = cluster.HistoryFailed
+		if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+			entry.Result = cluster.HistoryInterrupted
+		}
+		entry.Error = err.Error()
+	}
+	if err := operation.cluster.RecordHistory(entry); err != nil {
+		utils.Logger.Warnf("Can't record the operation into the history: %v", err)
+	}
+}
+
+// currentUser returns the name of the user running vind.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// affectedMachines returns the machines named by the arguments and the
+// machines given by --to, if the command has it, as machine, MachineSet names
+// or patterns, like "node0,node1 | node2" for partition. Without arguments,
+// all the machines of the cluster are affected.
+func affectedMachines(cmd *cobra.Command, c *cluster.Cluster) []string {
+	args := cmd.Flags().Args()
+	if to, err := cmd.Flags().GetStringSlice("to"); err == nil {
+		args = append(slices.Clone(args), to...)
+	}
+	var machines []*cluster.Machine
+	if len(args) < 1 {
+		machines, _ = c.GetMachinesInSet("")
+	}
+	for _, arg := range args {
+		for _, name := range strings.FieldsFunc(arg, func(r rune) bool { return r == ',' || r == '|' || r == ' ' }) {
+			// the names matching nothing failed the command already
+			matches, _ := c.GetMachines([]string{name})
+			machines = append(machines, matches...)
+		}
+	}
+	var names []string
+	for _, m := range machines {
+		if !slices.Contains(names, m.MachineName()) {
+			names = append(names, m.MachineName())
+		}
+	}
+	return names
+}
+
+var logOptions struct {
+	level  string
+	format string
+	quiet  bool
+}
+
+// setUp configures the logs, and the dry run if any, before any command.
+func setUp(cmd *cobra.Command, args []string) error {
+	if err := utils.ConfigureLogger(logOptions.level, logOptions.format, logOptions.quiet); err != nil {
+		return err
+	}
+	startDryRun()
+	return nil
+}
+
+var dryRunOptions struct {
+	dryRun     bool
+	emitScript string
+}
+
+// recorder records the commands of dry runs.
+var recorder *exec.Recorder
+
+// startDryRun records the commands which change the state of docker, instead
+// of running them, when asked to. The docker queries still run.
+func startDryRun() {
+	if !dryRunOptions.dryRun && dryRunOptions.emitScript == "" {
+		return
+	}
+	recorder = &exec.Recorder{
+		Cmder:       exec.DefaultCmder,
+		Passthrough: docker.IsQuery,
+	}
+	exec.DefaultCmder = recorder
+}
+
+// emitScript writes the commands recorded by the dry run as a shell script,
+// "-" standing for the standard output.
+func emitScript(cmd *cobra.Command, args []string) error {
+	if recorder == nil || dryRunOptions.emitScript == "" {
+		return nil
+	}
+	var script bytes.Buffer
+	header := fmt.Sprintf("Generated by \"%s\", to run without vind", cmd.CommandPath())
+	if err := exec.WriteScript(&script, header, recorder.Commands()); err != nil {
+		return err
+	}
+	if dryRunOptions.emitScript == "-" {
+		_, err := os.Stdout.Write(script.Bytes())
+		return err
+	}
+	utils.Logger.Infof("Writing script: %s ...", dryRunOptions.emitScript)
+	return os.WriteFile(dryRunOptions.emitScript, script.Bytes(), 0755)
+}
+
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile.config, "config", "c", "", "Cluster configuration file")
+	rootCmd.PersistentFlags().StringVar(&logOptions.level, "log-level", utils.DefaultLogLevel(), "Log level: {panic,fatal,error,warn,info,debug,trace}, $"+utils.LogLevelEnv+" by default")
+	rootCmd.PersistentFlags().StringVar(&logOptions.format, "log-format", "text", "Log format: {text,json}")
+	rootCmd.PersistentFlags().BoolVarP(&logOptions.quiet, "quiet", "q", false, "Only log errors, like --log-level error")
+	rootCmd.PersistentFlags().DurationVar(&lockOptions.wait, "wait-lock", 0, "How long to wait for another vind process changing the cluster to finish, 0 to fail right away")
+	rootCmd.PersistentFlags().BoolVar(&dryRunOptions.dryRun, "dry-run", false, "Print the docker commands changing anything instead of running them")
+	rootCmd.PersistentFlags().StringVar(&dryRunOptions.emitScript, "emit-script", "", "Write the docker commands of a dry run as a shell script to the file, - for the standard output")
+	rootCmd.PersistentFlags().DurationVar(&cluster.DefaultTimeouts.Pull, "pull-timeout", cluster.DefaultTimeouts.Pull, "Timeout of the pull of each image, 0 for none")
+	rootCmd.PersistentFlags().DurationVar(&cluster.DefaultTimeouts.Start, "start-timeout", cluster.DefaultTimeouts.Start, "Timeout of the start of each machine, 0 for none")
+	rootCmd.PersistentFlags().DurationVar(&cluster.DefaultTimeouts.Provision, "provision-timeout", cluster.DefaultTimeouts.Provision, "Timeout of the provisioning of each machine, 0 for none")
+	rootCmd.PersistentFlags().DurationVar(&cluster.DefaultTimeouts.SSH, "ssh-timeout", cluster.DefaultTimeouts.SSH, "Timeout of the wait for the SSH server of a machine, 0 for none")
 }
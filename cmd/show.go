@@ -35,11 +35,13 @@ var showCmd = &cobra.Command{
 }
 
 var showOptions struct {
-	output string
+	output       string
+	proxyCommand bool
 }
 
 func init() {
 	showCmd.Flags().StringVarP(&showOptions.output, "output", "o", "table", "Output formatting options: {table,json,ansible,ssh}.")
+	showCmd.Flags().BoolVar(&showOptions.proxyCommand, "proxy-command", false, "Tunnel SSH over docker exec in the ssh output, even for machines with a published SSH port")
 	rootCmd.AddCommand(showCmd)
 }
 
@@ -58,11 +60,11 @@ func show(cmd *cobra.Command, args []string) error {
 	case "ansible":
 		formatter = new(cluster.AnsibleFormatter)
 	case "ssh":
-		formatter = new(cluster.SSHConfigFormatter)
+		formatter = &cluster.SSHConfigFormatter{ProxyCommand: showOptions.proxyCommand}
 	default:
 		return fmt.Errorf("unknown formatter '%s'", showOptions.output)
 	}
-	machines, err := c.Show(args)
+	machines, err := c.Show(cmd.Context(), args)
 	if err != nil {
 		return err
 	}
@@ -18,6 +18,8 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -30,20 +32,41 @@ import (
 var sshCmd = &cobra.Command{
 	Use:   "ssh [[USER@]<MACHINE_NAME>]",
 	Short: "SSH into a specific machine, or first machine if not specified",
-	Args:  validateSSHArgs,
-	RunE:  ssh,
+	Long: `SSH into a specific machine, or first machine if not specified
+
+The machine is reached by SSH through its published port 22. If the port isn't
+published, or with "--via exec", a login shell is opened through docker exec instead.
+
+With "--all" or "--machineset", a tmux session is opened instead, with one pane
+per machine SSHed into. Add "--sync" to type in all the panes at once.
+`,
+	Args: validateSSHArgs,
+	RunE: ssh,
 }
 
 var configOptions struct {
 	extraSshArgs string
+	via          string
+	all          bool
+	machineSet   string
+	synchronize  bool
 }
 
 func init() {
 	sshCmd.Flags().StringVarP(&configOptions.extraSshArgs, "extra-ssh-args", "e", "", "Extra args for SSH command")
+	sshCmd.Flags().StringVar(&configOptions.via, "via", c.SSHViaAuto, "How to reach the machine: {auto,ssh,exec}")
+	sshCmd.Flags().BoolVarP(&configOptions.all, "all", "a", false, "Open a tmux pane for each machine of the cluster")
+	sshCmd.Flags().StringVarP(&configOptions.machineSet, "machineset", "m", "", "Open a tmux pane for each machine of the MachineSet")
+	sshCmd.Flags().BoolVar(&configOptions.synchronize, "sync", false, "Send the input to all the tmux panes")
+	sshCmd.MarkFlagsMutuallyExclusive("all", "machineset")
 	rootCmd.AddCommand(sshCmd)
 }
 
 func ssh(cmd *cobra.Command, args []string) error {
+	if configOptions.all || configOptions.machineSet != "" {
+		return sshMultiplex()
+	}
+
 	cluster, err := c.NewFromFile(configFile(cfgFile.config))
 	if err != nil {
 		return err
@@ -72,7 +95,7 @@ func ssh(cmd *cobra.Command, args []string) error {
 
 		machine, err = cluster.GetMachineByMachineName(machineName)
 		if err != nil {
-			return fmt.Errorf("machine name not found: %s", machineName)
+			return err
 		}
 	}
 
@@ -80,12 +103,45 @@ func ssh(cmd *cobra.Command, args []string) error {
 		userName = machine.User()
 	}
 
-	return cluster.SSH(machine, userName, configOptions.extraSshArgs)
+	return cluster.SSH(cmd.Context(), machine, userName, configOptions.extraSshArgs, configOptions.via)
+}
+
+// sshMultiplex opens a tmux pane running "vind ssh" for each selected machine.
+func sshMultiplex() error {
+	config, err := filepath.Abs(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	cluster, err := c.NewFromFile(config)
+	if err != nil {
+		return err
+	}
+	machines, err := cluster.GetMachinesInSet(configOptions.machineSet)
+	if err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	command := func(machine *c.Machine) []string {
+		args := []string{self, "ssh", machine.MachineName(), "-c", config, "--via", configOptions.via}
+		if configOptions.extraSshArgs != "" {
+			args = append(args, "-e", configOptions.extraSshArgs)
+		}
+		return args
+	}
+	return cluster.Multiplex(machines, command, configOptions.synchronize)
 }
 
 func validateSSHArgs(cmd *cobra.Command, args []string) error {
 	if len(args) > 1 {
 		return errors.New("too many args")
 	}
+	all, _ := cmd.Flags().GetBool("all")
+	machineSet, _ := cmd.Flags().GetString("machineset")
+	if len(args) > 0 && (all || machineSet != "") {
+		return errors.New("no machine can be given with --all or --machineset")
+	}
 	return nil
 }
@@ -37,5 +37,10 @@ func start(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	return cluster.Start(args)
+	unlock, err := lockCluster(cmd, cluster)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return reportInterrupted(cmd.Context(), cluster, cluster.Start(cmd.Context(), args))
 }
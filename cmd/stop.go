@@ -37,5 +37,10 @@ func stop(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	return cluster.Stop(args)
+	unlock, err := lockCluster(cmd, cluster)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return reportInterrupted(cmd.Context(), cluster, cluster.Stop(cmd.Context(), args))
 }
@@ -0,0 +1,73 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	c "github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync <HOST_DIR> <MACHINE_NAME:DEST_DIR>",
+	Short: "Sync a host directory into machines, continuously",
+	Long: `Sync a host directory into machines, continuously
+
+The content of HOST_DIR is pushed into DEST_DIR of the machines, then HOST_DIR is
+watched and its changes are pushed as they happen, until interrupted. The machine
+name can be a pattern, like 'workers-*', to sync to all the matching machines.
+
+This is an alternative to bind mounting the host root into privileged machines.
+`,
+	Example: `  vind sync . test-node0:/src
+  vind sync ./app 'workers-*:/opt/app' --ignore '*.log' --ignore node_modules
+  vind sync ./app 'workers-*:/opt/app' --once`,
+	Args: cobra.ExactArgs(2),
+	RunE: sync,
+}
+
+var syncOptions = c.SyncOptions{}
+
+func init() {
+	syncCmd.Flags().StringSliceVarP(&syncOptions.Ignore, "ignore", "i", []string{".git"}, "Patterns of the paths, or base names, not to sync")
+	syncCmd.Flags().BoolVar(&syncOptions.Delete, "delete", true, "Delete from the machines the files deleted on the host")
+	syncCmd.Flags().DurationVar(&syncOptions.Batch, "batch", 200*time.Millisecond, "How long changes are collected before being pushed")
+	syncCmd.Flags().BoolVar(&syncOptions.Once, "once", false, "Sync once, without watching for changes")
+	syncCmd.Flags().BoolVarP(&syncOptions.Archive, "archive", "a", false, "Archive mode, keeping the uid/gid of the synced files")
+	syncCmd.Flags().StringVar(&syncOptions.Owner, "chown", "", "Set the owner, like user[:group], of the synced files")
+	syncCmd.Flags().StringVar(&syncOptions.Mode, "chmod", "", "Set the mode, like 755 or u+x, of the synced files")
+	rootCmd.AddCommand(syncCmd)
+}
+
+func sync(cmd *cobra.Command, args []string) error {
+	cluster, err := c.NewFromFile(configFile(cfgFile.config))
+	if err != nil {
+		return err
+	}
+	pattern, destDir, ok := splitMachinePath(args[1])
+	if !ok || destDir == "" {
+		return errors.New("dest must be like MACHINE_NAME:DEST_DIR")
+	}
+	machines, err := cluster.GetMachinesByPattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	return cluster.Sync(cmd.Context(), args[0], machines, destDir, syncOptions)
+}
@@ -2,6 +2,8 @@ module github.com/brightzheng100/vind
 
 require (
 	github.com/docker/docker v1.13.1
+	github.com/docker/go-connections v0.4.0
+	github.com/fsnotify/fsnotify v1.8.0
 	github.com/ghodss/yaml v1.0.0
 	github.com/google/go-github/v24 v24.0.1
 	github.com/mitchellh/go-homedir v1.1.0
@@ -9,20 +11,20 @@ require (
 	github.com/sirupsen/logrus v1.3.0
 	github.com/spf13/cobra v1.8.1
 	github.com/stretchr/testify v1.2.2
+	golang.org/x/crypto v0.31.0
+	golang.org/x/sys v0.28.0
 	gopkg.in/yaml.v2 v2.2.2
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/docker/go-connections v0.4.0 // indirect
 	github.com/docker/go-units v0.3.3 // indirect
 	github.com/google/go-querystring v1.0.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2 // indirect
-	golang.org/x/sys v0.0.0-20220615213510-4f61da869c0c // indirect
+	golang.org/x/term v0.27.0 // indirect
 )
 
 go 1.23
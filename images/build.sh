@@ -10,8 +10,9 @@ for distro in "25.04" "24.10" "24.04" "22.04" "20.04" "18.04"; do
 
 pushd ubuntu
 
-    docker buildx build --platform linux/amd64,linux/arm64 --file Dockerfile.${distro}.non-root --${image_action} -t ${repo_namespace}/vind-ubuntu:${distro} .
-    docker buildx build --platform linux/amd64,linux/arm64 --file Dockerfile.${distro}.root --${image_action} -t ${repo_namespace}/vind-ubuntu-root:${distro} .
+    # Non-root users are created by vind at provisioning time, see "users" in the machine spec.
+    # The "vind-ubuntu-root" tag is kept for existing configurations.
+    docker buildx build --platform linux/amd64,linux/arm64 --file Dockerfile.${distro} --${image_action} -t ${repo_namespace}/vind-ubuntu:${distro} -t ${repo_namespace}/vind-ubuntu-root:${distro} .
 
 popd
 
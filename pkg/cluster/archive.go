@@ -0,0 +1,84 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// tarRoots returns the top-level entries of a tar archive, in order.
+func tarRoots(data []byte) ([]string, error) {
+	var roots []string
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return roots, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "read archive")
+		}
+		root, _, _ := strings.Cut(strings.TrimPrefix(hdr.Name, "./"), "/")
+		if root == "" || root == "." || slices.Contains(roots, root) {
+			continue
+		}
+		roots = append(roots, root)
+	}
+}
+
+// renameTarRoot renames the top-level entry root of a tar archive, and all
+// the entries under it, to name.
+func renameTarRoot(data []byte, root string, name string) ([]byte, error) {
+	rename := func(entry string) string {
+		if entry == root || strings.HasPrefix(entry, root+"/") {
+			return name + strings.TrimPrefix(entry, root)
+		}
+		return entry
+	}
+
+	var renamed bytes.Buffer
+	tr := tar.NewReader(bytes.NewReader(data))
+	tw := tar.NewWriter(&renamed)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "read archive")
+		}
+		hdr.Name = rename(hdr.Name)
+		if hdr.Typeflag == tar.TypeLink {
+			hdr.Linkname = rename(hdr.Linkname)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, errors.Wrap(err, "write archive")
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return nil, errors.Wrap(err, "write archive")
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, errors.Wrap(err, "write archive")
+	}
+	return renamed.Bytes(), nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tarOf(t *testing.T, entries ...*tar.Header) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range entries {
+		content := ""
+		if hdr.Typeflag == tar.TypeReg {
+			content = "content of " + hdr.Name
+			hdr.Size = int64(len(content))
+		}
+		assert.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func tarEntries(t *testing.T, data []byte) map[string]string {
+	entries := map[string]string{}
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		assert.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		assert.NoError(t, err)
+		entries[hdr.Name] = string(content) + hdr.Linkname
+	}
+}
+
+func TestTarRoots(t *testing.T) {
+	data := tarOf(t,
+		&tar.Header{Name: "./", Typeflag: tar.TypeDir},
+		&tar.Header{Name: "./bin/", Typeflag: tar.TypeDir},
+		&tar.Header{Name: "./bin/vind", Typeflag: tar.TypeReg},
+		&tar.Header{Name: "etc/", Typeflag: tar.TypeDir},
+		&tar.Header{Name: "README.md", Typeflag: tar.TypeReg},
+	)
+	roots, err := tarRoots(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bin", "etc", "README.md"}, roots)
+
+	_, err = tarRoots([]byte("not a tar archive"))
+	assert.Error(t, err)
+}
+
+func TestRenameTarRoot(t *testing.T) {
+	data := tarOf(t,
+		&tar.Header{Name: "x/", Typeflag: tar.TypeDir},
+		&tar.Header{Name: "x/a", Typeflag: tar.TypeReg},
+		&tar.Header{Name: "x/b", Typeflag: tar.TypeLink, Linkname: "x/a"},
+		&tar.Header{Name: "xy", Typeflag: tar.TypeReg},
+	)
+	renamed, err := renameTarRoot(data, "x", "y")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"y/":  "",
+		"y/a": "content of x/a",
+		"y/b": "y/a",
+		"xy":  "content of xy",
+	}, tarEntries(t, renamed))
+}
+
+func TestJoinAll(t *testing.T) {
+	assert.Equal(t, []string{"/etc/a", "/etc/b"}, joinAll("/etc/", []string{"a", "b"}))
+	assert.Empty(t, joinAll("/etc", nil))
+}
@@ -0,0 +1,288 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/pkg/errors"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// CA_KEY_PATH is where the certificate authority public key is installed on the machines.
+const CA_KEY_PATH = "/etc/ssh/vind_user_ca.pub"
+
+// CA_SSHD_SCRIPT puts the sshd configuration block read from stdin at the top of
+// sshd_config, replacing the previous one, and reloads sshd.
+const CA_SSHD_SCRIPT = `
+set -e
+f=/etc/ssh/sshd_config
+t=$(mktemp)
+trap 'rm -f "$t"' EXIT
+cat > "$t"
+sed '/^# BEGIN vind$/,/^# END vind$/d' "$f" >> "$t"
+cat "$t" > "$f"
+systemctl reload ssh 2>/dev/null || systemctl reload sshd 2>/dev/null || true
+`
+
+// caComment is the comment of the certificate authority keys.
+const caComment = "vind-ca"
+
+// defaultCertTTL is the default validity of the user certificates signed for "vind ssh".
+const defaultCertTTL = time.Hour
+
+// certClockSkew backdates the certificates to tolerate clock differences.
+const certClockSkew = 5 * time.Minute
+
+// caEnabled returns whether the cluster has a certificate authority.
+func (c *Cluster) caEnabled() bool {
+	return c.config.Cluster.CertificateAuthority != nil && c.config.Cluster.CertificateAuthority.Enabled
+}
+
+// caPath returns the path of the certificate authority private key.
+func (c *Cluster) caPath() string {
+	return filepath.Join(c.Dir(), "ca")
+}
+
+// certTTL returns the validity of the user certificates signed for "vind ssh".
+func (c *Cluster) certTTL() time.Duration {
+	if ca := c.config.Cluster.CertificateAuthority; ca != nil && ca.TTL != "" {
+		if ttl, err := time.ParseDuration(ca.TTL); err == nil {
+			return ttl
+		}
+	}
+	return defaultCertTTL
+}
+
+// certificateAuthority loads the certificate authority of the cluster,
+// generating it at first use.
+func (c *Cluster) certificateAuthority() (gossh.Signer, error) {
+	if !c.caEnabled() {
+		return nil, errors.Errorf("no certificate authority is enabled for cluster %s", c.Name())
+	}
+	path := c.caPath()
+	if !fileExists(path) {
+		c.logger().Infof("Creating SSH certificate authority: %s ...", path)
+		private, public, err := generateKey(config.KeyTypeED25519, f("%s@%s", caComment, c.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if c.dryRun {
+			// a throwaway one, for the certificates to be signed anyway
+			c.logger().Infof("Dry run: writing %s", path)
+			return gossh.ParsePrivateKey(private)
+		}
+		if err := writeKey(path, private, public); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "ca: read private key")
+	}
+	signer, err := gossh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "ca: parse private key")
+	}
+	return signer, nil
+}
+
+// CAPublicKey returns the certificate authority public key in the
+// authorized_keys format.
+func (c *Cluster) CAPublicKey() ([]byte, error) {
+	ca, err := c.certificateAuthority()
+	if err != nil {
+		return nil, err
+	}
+	return gossh.MarshalAuthorizedKey(ca.PublicKey()), nil
+}
+
+// signCertificate signs a user or host certificate of the key, valid for the
+// principals during the ttl, or forever if the ttl is 0.
+func signCertificate(ca gossh.Signer, key gossh.PublicKey, certType uint32, keyID string, principals []string, ttl time.Duration) (*gossh.Certificate, error) {
+	now := time.Now()
+	cert := &gossh.Certificate{
+		Key:             key,
+		CertType:        certType,
+		KeyId:           keyID,
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(now.Add(-certClockSkew).Unix()),
+		ValidBefore:     gossh.CertTimeInfinity,
+	}
+	if ttl > 0 {
+		cert.ValidBefore = uint64(now.Add(ttl).Unix())
+	}
+	if certType == gossh.UserCert {
+		cert.Permissions.Extensions = map[string]string{
+			"permit-X11-forwarding":   "",
+			"permit-agent-forwarding": "",
+			"permit-port-forwarding":  "",
+			"permit-pty":              "",
+			"permit-user-rc":          "",
+		}
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		return nil, errors.Wrap(err, "ca: sign certificate")
+	}
+	return cert, nil
+}
+
+// SignUserKey signs a user certificate of the public key, in the
+// authorized_keys format, for the principals during the ttl.
+func (c *Cluster) SignUserKey(publicKey []byte, principals []string, ttl time.Duration) (*gossh.Certificate, error) {
+	ca, err := c.certificateAuthority()
+	if err != nil {
+		return nil, err
+	}
+	key, comment, _, _, err := gossh.ParseAuthorizedKey(publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "ca: parse public key")
+	}
+	keyID := comment
+	if keyID == "" {
+		keyID = strings.Join(principals, ",")
+	}
+	keyID = f("%s@%s", keyID, c.Name())
+	c.logger().Infof("Signing certificate %s for principals %v, valid for %v", keyID, principals, ttl)
+	return signCertificate(ca, key, gossh.UserCert, keyID, principals, ttl)
+}
+
+// userCertificate signs a short-lived certificate of the cluster key for the
+// user and writes it into the cluster folder, rather than next to the key
+// which may be shared with other clusters or have its own certificate.
+func (c *Cluster) userCertificate(user string) (string, error) {
+	path := c.privateKeyPath()
+	publicKey, err := os.ReadFile(path + ".pub")
+	if err != nil {
+		return "", errors.Wrap(err, "ca: read cluster public key")
+	}
+	cert, err := c.SignUserKey(publicKey, []string{user}, c.certTTL())
+	if err != nil {
+		return "", err
+	}
+	certPath := filepath.Join(c.Dir(), user+"-cert.pub")
+	if c.dryRun {
+		c.logger().Infof("Dry run: writing %s", certPath)
+		return certPath, nil
+	}
+	if err := os.MkdirAll(c.Dir(), 0700); err != nil {
+		return "", errors.Wrap(err, "ca: create cluster folder")
+	}
+	if err := os.WriteFile(certPath, gossh.MarshalAuthorizedKey(cert), 0644); err != nil {
+		return "", errors.Wrap(err, "ca: write certificate")
+	}
+	return certPath, nil
+}
+
+// hostKeyName returns the name used by the host key files of the key type,
+// e.g. "ed25519" for /etc/ssh/ssh_host_ed25519_key.
+func hostKeyName(keyType string) string {
+	switch {
+	case keyType == gossh.KeyAlgoED25519:
+		return "ed25519"
+	case strings.HasPrefix(keyType, "ecdsa-"):
+		return "ecdsa"
+	case keyType == gossh.KeyAlgoRSA:
+		return "rsa"
+	case keyType == gossh.KeyAlgoDSA:
+		return "dsa"
+	}
+	return ""
+}
+
+// configureCA installs the certificate authority into a running machine: sshd
+// trusts the user certificates it signs and presents host certificates signed
+// by it.
+func (c *Cluster) configureCA(ctx context.Context, m *Machine) error {
+	ca, err := c.certificateAuthority()
+	if err != nil {
+		return err
+	}
+	m.logger().Infof("Configuring SSH certificate authority on machine %s ...", m.machineName)
+	if err := m.writeFile(ctx, gossh.MarshalAuthorizedKey(ca.PublicKey()), CA_KEY_PATH, "root:", 0644); err != nil {
+		return err
+	}
+
+	keys, err := m.hostKeys(ctx)
+	if err != nil {
+		return err
+	}
+	principals := []string{m.machineName, m.containerName, "localhost", "127.0.0.1", "::1"}
+	if bindings, err := m.HostPorts(22, "tcp"); err == nil {
+		if host, _ := sshEndpoint(bindings); !slices.Contains(principals, host) {
+			principals = append(principals, host)
+		}
+	}
+	if networks, err := m.networks(); err == nil {
+		for _, network := range networks {
+			if network.IP != "" && !slices.Contains(principals, network.IP) {
+				principals = append(principals, network.IP)
+			}
+		}
+	}
+
+	var block bytes.Buffer
+	block.WriteString("# BEGIN vind\n")
+	block.WriteString(f("TrustedUserCAKeys %s\n", CA_KEY_PATH))
+	for _, key := range keys {
+		name := hostKeyName(key.Type())
+		if name == "" {
+			continue
+		}
+		cert, err := signCertificate(ca, key, gossh.HostCert, m.containerName, principals, 0)
+		if err != nil {
+			return err
+		}
+		certPath := f("/etc/ssh/ssh_host_%s_key-cert.pub", name)
+		if err := m.writeFile(ctx, gossh.MarshalAuthorizedKey(cert), certPath, "root:", 0644); err != nil {
+			return err
+		}
+		block.WriteString(f("HostCertificate %s\n", certPath))
+	}
+	block.WriteString("# END vind\n")
+	if err := m.runInput(ctx, block.Bytes(), "/bin/sh", "-c", CA_SSHD_SCRIPT); err != nil {
+		return err
+	}
+
+	return c.updateKnownHostsFile(func(content []byte) []byte {
+		return trustCertificateAuthority(content, ca.PublicKey())
+	})
+}
+
+// trustCertificateAuthority replaces the certificate authority line of the
+// known_hosts file content, trusting the host certificates it signs.
+func trustCertificateAuthority(content []byte, key gossh.PublicKey) []byte {
+	var updated bytes.Buffer
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || (fields[0] == "@cert-authority" && fields[len(fields)-1] == caComment) {
+			continue
+		}
+		updated.WriteString(line)
+		updated.WriteByte('\n')
+	}
+	updated.WriteString("@cert-authority " + knownhosts.Line([]string{"*"}, key) + " " + caComment + "\n")
+	return updated.Bytes()
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/stretchr/testify/assert"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestSignCertificate(t *testing.T) {
+	caKey, _, err := generateKey(config.KeyTypeED25519, caComment)
+	assert.NoError(t, err)
+	ca, err := gossh.ParsePrivateKey(caKey)
+	assert.NoError(t, err)
+	_, pub, err := generateKey(config.KeyTypeECDSA, "")
+	assert.NoError(t, err)
+	key := publicKeyOf(t, pub)
+
+	cert, err := signCertificate(ca, key, gossh.UserCert, "test", []string{"ubuntu"}, time.Hour)
+	assert.NoError(t, err)
+	checker := &gossh.CertChecker{
+		IsUserAuthority: func(auth gossh.PublicKey) bool {
+			return string(auth.Marshal()) == string(ca.PublicKey().Marshal())
+		},
+	}
+	assert.NoError(t, checker.CheckCert("ubuntu", cert))
+	assert.Error(t, checker.CheckCert("root", cert))
+	assert.Contains(t, cert.Permissions.Extensions, "permit-pty")
+	assert.True(t, cert.ValidBefore <= uint64(time.Now().Add(time.Hour).Unix()))
+
+	cert, err = signCertificate(ca, key, gossh.HostCert, "test", []string{"localhost"}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(gossh.CertTimeInfinity), cert.ValidBefore)
+	assert.Empty(t, cert.Permissions.Extensions)
+}
+
+func TestUserCertificate(t *testing.T) {
+	t.Setenv("VIND_HOME", t.TempDir())
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	private, public, err := generateKey(config.KeyTypeED25519, "")
+	assert.NoError(t, err)
+	assert.NoError(t, writeKey(keyPath, private, public))
+	c, err := NewFromYAML([]byte(`
+cluster:
+  name: test
+  privateKey: ` + keyPath + `
+  certificateAuthority:
+    enabled: true
+machineSets:
+- name: nodes
+  replicas: 1
+  spec:
+    image: quay.io/brightzheng100/ubuntu22.04
+    name: node%d
+`))
+	assert.NoError(t, err)
+
+	certPath, err := c.userCertificate("ubuntu")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(c.Dir(), "ubuntu-cert.pub"), certPath)
+	assert.FileExists(t, certPath)
+	_, err = os.Stat(keyPath + "-cert.pub")
+	assert.True(t, os.IsNotExist(err), "%v", err)
+}
+
+func TestHostKeyName(t *testing.T) {
+	assert.Equal(t, "ed25519", hostKeyName(gossh.KeyAlgoED25519))
+	assert.Equal(t, "ecdsa", hostKeyName(gossh.KeyAlgoECDSA256))
+	assert.Equal(t, "rsa", hostKeyName(gossh.KeyAlgoRSA))
+	assert.Equal(t, "", hostKeyName("unknown"))
+}
+
+func TestTrustCertificateAuthority(t *testing.T) {
+	_, pub1, _ := generateKey(config.KeyTypeED25519, "")
+	_, pub2, _ := generateKey(config.KeyTypeED25519, "")
+	key1, key2 := publicKeyOf(t, pub1), publicKeyOf(t, pub2)
+
+	content := []byte("[localhost]:32768 ssh-ed25519 AAAA cluster-test-node0\n")
+	content = trustCertificateAuthority(content, key1)
+	content = trustCertificateAuthority(content, key2)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, "[localhost]:32768 ssh-ed25519 AAAA cluster-test-node0", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "@cert-authority * ssh-ed25519 "))
+	assert.True(t, strings.HasSuffix(lines[1], " "+caComment))
+	assert.Contains(t, lines[1], strings.TrimSpace(string(pub2)))
+}
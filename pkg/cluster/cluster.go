@@ -17,9 +17,12 @@ limitations under the License.
 package cluster
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"regexp"
 	"slices"
 	"strconv"
@@ -27,19 +30,25 @@ import (
 	"time"
 
 	"github.com/brightzheng100/vind/pkg/config"
-	"github.com/brightzheng100/vind/pkg/docker"
 	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/brightzheng100/vind/pkg/runtime"
 	"github.com/brightzheng100/vind/pkg/utils"
-	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
 	"github.com/ghodss/yaml"
-	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 )
 
-// cluster is a running cluster.
-type cluster struct {
+// Cluster is a vind cluster, the machines of its configuration. It is the
+// entry point of the Go API of vind: its methods create, start, stop and
+// delete the machines, run commands and copy files into them.
+type Cluster struct {
 	config   config.Config
 	keyStore *KeyStore
+	runtime  runtime.Runtime
+	timeouts Timeouts
+	// dryRun leaves the local files, the state of the cluster and the
+	// processes alone, see SetDryRun.
+	dryRun bool
 }
 
 // Container represents a running machine.
@@ -49,18 +58,22 @@ type Container struct {
 
 // New creates a new cluster. It takes as input the description of the cluster
 // and its machines.
-func New(conf config.Config) (*cluster, error) {
+func New(conf config.Config) (*Cluster, error) {
 	if err := conf.Validate(); err != nil {
 		return nil, err
 	}
-	return &cluster{
-		config: conf,
+	return &Cluster{
+		config:   conf,
+		keyStore: DefaultKeyStore(),
+		runtime:  runtime.Docker{},
+		timeouts: DefaultTimeouts,
+		dryRun:   exec.DryRun(),
 	}, nil
 }
 
 // NewFromYAML creates a new Cluster from a YAML serialization of its
 // configuration available in the provided string.
-func NewFromYAML(data []byte) (*cluster, error) {
+func NewFromYAML(data []byte) (*Cluster, error) {
 	config := config.Config{}
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, err
@@ -70,7 +83,7 @@ func NewFromYAML(data []byte) (*cluster, error) {
 
 // NewFromFile creates a new Cluster from a YAML serialization of its
 // configuration available in the provided file.
-func NewFromFile(path string) (*cluster, error) {
+func NewFromFile(path string) (*Cluster, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -78,11 +91,20 @@ func NewFromFile(path string) (*cluster, error) {
 	return NewFromYAML(data)
 }
 
+// newMachine inits the indexed Machine of the MachineSet, run by the runtime
+// of the cluster.
+func (c *Cluster) newMachine(machineSet *config.MachineSet, i int) *Machine {
+	m := newMachine(&c.config.Cluster, machineSet, &machineSet.Spec, i)
+	m.runtime = c.runtime
+	m.timeouts = c.timeouts
+	return m
+}
+
 // forEachMachine loops through every Machine for doing something
-func (c *cluster) forEachMachine(do func(*Machine) error) error {
+func (c *Cluster) forEachMachine(do func(*Machine) error) error {
 	for _, machineSet := range c.config.MachineSets {
 		for i := 0; i < machineSet.Replicas; i++ {
-			machine := newMachine(&c.config.Cluster, &machineSet, &machineSet.Spec, i)
+			machine := c.newMachine(&machineSet, i)
 			if err := do(machine); err != nil {
 				return err
 			}
@@ -91,93 +113,167 @@ func (c *cluster) forEachMachine(do func(*Machine) error) error {
 	return nil
 }
 
-// forEachMachine loops through all Machine and locates only specific ones for doing something
-func (c *cluster) forSpecificMachines(do func(*Machine) error, machineNames []string) error {
-	// machineToStart map is used to track machines to make actions and non existing machines
-	machineToHandle := make(map[string]bool)
-	for _, machine := range machineNames {
-		machineToHandle[machine] = false
+// forSpecificMachines loops through all Machine and locates only specific ones for doing something.
+// It fails with ErrMachineNotFound before doing anything if a machine doesn't exist.
+func (c *Cluster) forSpecificMachines(do func(*Machine) error, machineNames []string) error {
+	if err := c.checkMachineNames(machineNames); err != nil {
+		return err
 	}
-	for _, machineSet := range c.config.MachineSets {
-		for i := 0; i < machineSet.Replicas; i++ {
-			machine := newMachine(&c.config.Cluster, &machineSet, &machineSet.Spec, i)
-			if _, ok := machineToHandle[machine.machineName]; ok {
-				if err := do(machine); err != nil {
-					return err
-				}
-				machineToHandle[machine.machineName] = true
-			}
+	return c.forEachMachine(func(m *Machine) error {
+		if !slices.Contains(machineNames, m.machineName) {
+			return nil
+		}
+		return do(m)
+	})
+}
+
+// checkMachineNames returns ErrMachineNotFound for the first name which isn't
+// a machine of the cluster.
+func (c *Cluster) checkMachineNames(machineNames []string) error {
+	for _, name := range machineNames {
+		if _, err := c.GetMachineByMachineName(name); err != nil {
+			return err
 		}
 	}
-	// log warning for non existing machines
-	for key, value := range machineToHandle {
-		if !value {
-			utils.Logger.Warnf("machine %v does not exist", key)
+	return nil
+}
+
+// withContext makes do fail once the context is done, so that loops over
+// machines stop at the next one.
+func withContext(ctx context.Context, do func(*Machine) error) func(*Machine) error {
+	return func(m *Machine) error {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
+		return do(m)
 	}
+}
+
+// Create creates the cluster, and starts its machines. The machines which
+// already exist are left as is.
+func (c *Cluster) Create(ctx context.Context, opts CreateOptions) error {
+	start := time.Now()
+	cr := c.newCreation()
+	if err := c.create(ctx, cr); err != nil {
+		if opts.Atomic {
+			return c.rollback(cr, err)
+		}
+		return err
+	}
+	withOperation(c.logger(), "create", start).Infof("Created cluster %s", c.Name())
 	return nil
 }
 
-// Create creates the cluster.
-func (c *cluster) Create() error {
+// create creates the cluster, recording the machines it creates.
+func (c *Cluster) create(ctx context.Context, cr *creation) error {
 	// make sure the SSH key pair exists
 	if err := c.ensureSSHKey(); err != nil {
 		return err
 	}
 
 	// make sure Docker is running
-	if err := docker.IsRunning(); err != nil {
+	if err := c.runtime.IsRunning(ctx); err != nil {
 		return err
 	}
 
 	// pull the images if not exist
 	for _, template := range c.config.MachineSets {
-		if _, err := docker.PullIfNotPresent(template.Spec.Image, 2); err != nil {
+		image := template.Spec.Image
+		err := withTimeout(ctx, f("pulling image %s", image), c.timeouts.Pull, func(ctx context.Context) error {
+			return c.runtime.PullIfNotPresent(ctx, image)
+		})
+		if err != nil {
 			return err
 		}
 	}
 
 	// create all machines
-	return c.forEachMachine(func(m *Machine) error {
-		pk, err := c.publicKey(m.spec)
+	err := c.forEachMachine(withContext(ctx, func(m *Machine) error {
+		keys := map[string][]byte{}
+		for _, user := range m.Users() {
+			pk, err := c.publicKey(m.spec, user)
+			if err != nil {
+				return errors.Wrap(err, "can't retrieve public key")
+			}
+			keys[user] = pk
+		}
+		existed := m.IsCreated()
+		err := m.Create(ctx, &c.config.Cluster, keys)
+		if !existed && m.IsCreated() {
+			cr.machines = append(cr.machines, m)
+		}
 		if err != nil {
-			return errors.Wrap(err, "can't retrieve public key")
+			return err
 		}
-		return m.Create(&c.config.Cluster, pk)
-	})
+		return withTimeout(ctx, f("provisioning machine %s", m.machineName), c.timeouts.Provision, func(ctx context.Context) error {
+			if c.caEnabled() && m.IsStarted() {
+				if err := c.configureCA(ctx, m); err != nil {
+					return err
+				}
+			}
+			return c.refreshKnownHosts(ctx, m)
+		})
+	}))
+	if err != nil {
+		return err
+	}
+
+	// let the machines resolve each other by name
+	c.syncHosts(ctx)
+	return nil
 }
 
 // ensureSSHKey generates SSK key pair when needed
-func (c *cluster) ensureSSHKey() error {
-	if c.config.Cluster.PrivateKey == "" {
+func (c *Cluster) ensureSSHKey() error {
+	path := c.privateKeyPath()
+	if path == "" {
 		return nil
 	}
-	path, _ := homedir.Expand(c.config.Cluster.PrivateKey)
 	if _, err := os.Stat(path); err == nil {
 		return nil
 	}
 
-	utils.Logger.Infof("Creating SSH key: %s ...", path)
-	return run(
-		"ssh-keygen", "-q",
-		"-t", "rsa",
-		"-b", "4096",
-		"-C", f("%s@vind.mail", c.Name()),
-		"-f", path,
-		"-N", "",
-	)
+	c.logger().Infof("Creating SSH key: %s ...", path)
+	private, public, err := generateKey(c.config.Cluster.KeyType, f("%s@vind.mail", c.Name()))
+	if err != nil {
+		return err
+	}
+	return writeKey(path, private, public)
 }
 
-// publicKey retrieves the public key content from machine or clus
-func (c *cluster) publicKey(machine *config.Machine) ([]byte, error) {
-	// Prefer the machine public key over the cluster-wide key.
-	if machine.PublicKey != "" && c.keyStore != nil {
-		data, err := c.keyStore.Get(machine.PublicKey)
-		if err != nil {
-			return nil, err
+// publicKey retrieves the public keys authorized for the given user of the
+// machine. The machine public keys are preferred over the cluster-wide key for
+// the machine user.
+func (c *Cluster) publicKey(machine *config.Machine, user string) ([]byte, error) {
+	var names []string
+	if user == userOf(machine) {
+		if machine.PublicKey != "" {
+			names = append(names, machine.PublicKey)
+		}
+		names = append(names, machine.PublicKeys...)
+	} else {
+		names = append(names, machine.UserPublicKeys[user]...)
+	}
+	for _, u := range machine.Users {
+		if u.Name == user {
+			names = append(names, u.AuthorizedKeys...)
+		}
+	}
+
+	if len(names) > 0 && c.keyStore != nil {
+		var keys []byte
+		for _, name := range names {
+			data, err := c.keyStore.Get(name)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, bytes.TrimSpace(data)...)
+			keys = append(keys, byte('\n'))
 		}
-		data = append(data, byte('\n'))
-		return data, err
+		return keys, nil
+	}
+	if user != userOf(machine) {
+		return nil, nil
 	}
 
 	// Cluster global key
@@ -185,51 +281,103 @@ func (c *cluster) publicKey(machine *config.Machine) ([]byte, error) {
 		return nil, errors.New("no SSH key provided")
 	}
 
-	path, err := homedir.Expand(c.config.Cluster.PrivateKey)
-	if err != nil {
-		return nil, errors.Wrap(err, "public key expand")
-	}
-	return os.ReadFile(path + ".pub")
+	return os.ReadFile(c.privateKeyPath() + ".pub")
 }
 
 func f(format string, args ...interface{}) string {
 	return fmt.Sprintf(format, args...)
 }
 
+// SetRuntime sets the container runtime of the machines, Docker by default,
+// like an in-memory runtime.Fake in tests.
+func (c *Cluster) SetRuntime(rt runtime.Runtime) *Cluster {
+	c.runtime = rt
+	return c
+}
+
+// Runtime returns the container runtime of the machines.
+func (c *Cluster) Runtime() runtime.Runtime {
+	return c.runtime
+}
+
+// SetDryRun makes the operations leave the local files, like the keys and
+// the known hosts, the state of the cluster and the background processes
+// alone, logging what they would change instead. The commands run through
+// exec.DefaultCmder are recorded by an exec.Recorder instead, which sets it by
+// default.
+func (c *Cluster) SetDryRun(dryRun bool) *Cluster {
+	c.dryRun = dryRun
+	return c
+}
+
+// DryRun returns whether the operations are dry runs, see SetDryRun.
+func (c *Cluster) DryRun() bool {
+	return c.dryRun
+}
+
+// SetTimeouts sets the timeouts of the steps of the operations, the
+// DefaultTimeouts by default.
+func (c *Cluster) SetTimeouts(timeouts Timeouts) *Cluster {
+	c.timeouts = timeouts
+	return c
+}
+
 // SetKeyStore provides a store where to persist public keys for this Cluster.
-func (c *cluster) SetKeyStore(keyStore *KeyStore) *cluster {
+func (c *Cluster) SetKeyStore(keyStore *KeyStore) *Cluster {
 	c.keyStore = keyStore
 	return c
 }
 
 // Name returns the cluster name.
-func (c *cluster) Name() string {
+func (c *Cluster) Name() string {
 	return c.config.Cluster.Name
 }
 
 // Save writes the Cluster configure to a file.
-func (c *cluster) Save(path string) error {
+func (c *Cluster) Save(path string) error {
 	data, err := yaml.Marshal(c.config)
 	if err != nil {
 		return err
 	}
+	if c.dryRun {
+		c.logger().Infof("Dry run: writing configuration %s", path)
+		return nil
+	}
 	return os.WriteFile(path, data, 0666)
 }
 
-// Delete deletes the cluster.
-func (c *cluster) Delete() error {
-	if err := docker.IsRunning(); err != nil {
+// Delete deletes the cluster. The machines which don't exist are skipped.
+func (c *Cluster) Delete(ctx context.Context) error {
+	if err := c.runtime.IsRunning(ctx); err != nil {
 		return err
 	}
+	start := time.Now()
 
-	return c.forEachMachine(func(m *Machine) error {
-		return m.Delete()
-	})
+	err := c.forEachMachine(withContext(ctx, func(m *Machine) error {
+		if err := m.Delete(ctx); err != nil {
+			return err
+		}
+		return c.forgetKnownHosts(m)
+	}))
+	if err != nil {
+		return err
+	}
+	if err := c.forgetFaults(); err != nil {
+		return err
+	}
+	if err := c.forgetStaleKeys(); err != nil {
+		return err
+	}
+	withOperation(c.logger(), "delete", start).Infof("Deleted cluster %s", c.Name())
+	return nil
 }
 
 // Show will generate information about cluster's running or stopped machines.
-func (c *cluster) Show(machineNames []string) (machines []*Machine, err error) {
-	if err = docker.IsRunning(); err != nil {
+func (c *Cluster) Show(ctx context.Context, machineNames []string) (machines []*Machine, err error) {
+	if err = c.runtime.IsRunning(ctx); err != nil {
+		return nil, err
+	}
+	if err = c.checkMachineNames(machineNames); err != nil {
 		return nil, err
 	}
 
@@ -237,32 +385,39 @@ func (c *cluster) Show(machineNames []string) (machines []*Machine, err error) {
 	for _, machineSet := range c.config.MachineSets {
 		// walk through the specific machine set
 		for i := 0; i < machineSet.Replicas; i++ {
-			m := newMachine(&c.config.Cluster, &machineSet, &machineSet.Spec, i)
+			m := c.newMachine(&machineSet, i)
 
 			// Proceed only if no machine names specified or the machine name is included
 			if len(machineNames) == 0 || slices.Contains(machineNames, m.machineName) {
 				if !m.IsCreated() {
-					utils.Logger.Warnf("machine not created: %s", m.machineName)
+					m.logger().Warnf("machine not created: %s", m.machineName)
 					continue
 				}
 
-				var inspect types.ContainerJSON
-				if err := docker.InspectObject(m.containerName, ".", &inspect); err != nil {
+				inspect, err := c.runtime.Inspect(ctx, m.containerName)
+				if err != nil {
 					return machines, err
 				}
 
 				// Handle Ports
-				ports := make([]config.PortMapping, 0)
-				for k, v := range inspect.NetworkSettings.Ports {
-					if len(v) < 1 {
-						continue
+				m.portMap = inspect.NetworkSettings.Ports
+				published := make([]string, 0, len(m.portMap))
+				for k, v := range m.portMap {
+					if len(v) > 0 {
+						published = append(published, string(k))
 					}
-					p := config.PortMapping{}
-					hostPort, _ := strconv.Atoi(v[0].HostPort)
-					p.HostPort = uint16(hostPort)
-					p.ContainerPort = uint16(k.Int())
-					p.Address = v[0].HostIP
-					ports = append(ports, p)
+				}
+				slices.Sort(published)
+				ports := make([]config.PortMapping, 0, len(published))
+				for _, k := range published {
+					p := nat.Port(k)
+					hostPort, _ := strconv.Atoi(m.portMap[p][0].HostPort)
+					ports = append(ports, config.PortMapping{
+						Protocol:      p.Proto(),
+						Address:       m.portMap[p][0].HostIP,
+						HostPort:      uint16(hostPort),
+						ContainerPort: uint16(p.Int()),
+					})
 				}
 				m.spec.PortMappings = ports
 
@@ -273,7 +428,7 @@ func (c *cluster) Show(machineNames []string) (machines []*Machine, err error) {
 						Type:        string(mount.Type),
 						Source:      mount.Source,
 						Destination: mount.Destination,
-						ReadOnly:    mount.RW,
+						ReadOnly:    !mount.RW,
 					}
 					volumes = append(volumes, v)
 				}
@@ -291,42 +446,75 @@ func (c *cluster) Show(machineNames []string) (machines []*Machine, err error) {
 	return
 }
 
-// Start starts all or specific machines in cluster.
-func (c *cluster) Start(machineNames []string) error {
-	if err := docker.IsRunning(); err != nil {
+// Start starts all or specific machines in cluster. It fails with
+// ErrNotCreated for a machine which hasn't been created.
+func (c *Cluster) Start(ctx context.Context, machineNames []string) error {
+	if err := c.runtime.IsRunning(ctx); err != nil {
 		return err
 	}
+	start := time.Now()
 
-	startMachineFun := func(m *Machine) error {
-		return m.Start()
-	}
+	startMachineFun := withContext(ctx, func(m *Machine) error {
+		if err := m.Start(ctx); err != nil {
+			return err
+		}
+		// the SSH port may have changed
+		return withTimeout(ctx, f("provisioning machine %s", m.machineName), c.timeouts.Provision, func(ctx context.Context) error {
+			if err := c.authorizeStaleKeys(ctx, m); err != nil {
+				return err
+			}
+			return c.refreshKnownHosts(ctx, m)
+		})
+	})
 
-	// start all if no specific machines are specified
+	// start all if no specific machines are specified, otherwise the
+	// specific machines only
+	var err error
 	if len(machineNames) < 1 {
-		return c.forEachMachine(startMachineFun)
+		err = c.forEachMachine(startMachineFun)
+	} else {
+		err = c.forSpecificMachines(startMachineFun, machineNames)
+	}
+	if err != nil {
+		return err
 	}
 
-	// Otherwise, start the specific machines only
-	return c.forSpecificMachines(startMachineFun, machineNames)
+	// the IPs of the machines may have changed
+	c.syncHosts(ctx)
+	// and the rules of the network faults are gone
+	c.refreshFaults(ctx)
+	withOperation(c.logger(), "start", start).Infof("Started the machines of cluster %s", c.Name())
+	return nil
 }
 
-// Stop stops all or specific machines in cluster.
-func (c *cluster) Stop(machineNames []string) error {
-	if err := docker.IsRunning(); err != nil {
+// Stop stops all or specific machines in cluster. It fails with
+// ErrNotCreated for a machine which hasn't been created.
+func (c *Cluster) Stop(ctx context.Context, machineNames []string) error {
+	if err := c.runtime.IsRunning(ctx); err != nil {
 		return err
 	}
+	start := time.Now()
 
-	stopMachineFun := func(m *Machine) error {
-		return m.Stop()
-	}
+	stopMachineFun := withContext(ctx, func(m *Machine) error {
+		return m.Stop(ctx)
+	})
 
-	// stop all if no specific machines are specified
+	// stop all if no specific machines are specified, otherwise the
+	// specific machines only
+	var err error
 	if len(machineNames) < 1 {
-		return c.forEachMachine(stopMachineFun)
+		err = c.forEachMachine(stopMachineFun)
+	} else {
+		err = c.forSpecificMachines(stopMachineFun, machineNames)
+	}
+	if err != nil {
+		return err
 	}
 
-	// Otherwise, stop the specific machines only
-	return c.forSpecificMachines(stopMachineFun, machineNames)
+	// drop the stopped machines from the hosts files of the others
+	c.syncHosts(ctx)
+	withOperation(c.logger(), "stop", start).Infof("Stopped the machines of cluster %s", c.Name())
+	return nil
 }
 
 // io.Writer filter that writes that it receives to writer. Keeps track if it
@@ -388,23 +576,90 @@ func ssh(args []string) (bool, error) {
 	return false, err
 }
 
-func (c *cluster) GetMachineByMachineName(machineName string) (*Machine, error) {
+func (c *Cluster) GetMachineByMachineName(machineName string) (*Machine, error) {
 	for _, machineSet := range c.config.MachineSets {
 		for i := 0; i < machineSet.Replicas; i++ {
 			if machineName == f("%s-"+machineSet.Spec.Name, machineSet.Name, i) {
-				return newMachine(&c.config.Cluster, &machineSet, &machineSet.Spec, i), nil
+				return c.newMachine(&machineSet, i), nil
 			}
 		}
 	}
-	return nil, fmt.Errorf("Machine name not found: %s", machineName)
+	return nil, fmt.Errorf("%w: %s", ErrMachineNotFound, machineName)
 }
 
-func (c *cluster) GetFirstMachine() (*Machine, error) {
+// GetMachinesInSet returns the machines of the named MachineSet, or of all
+// the MachineSets if the name is empty.
+func (c *Cluster) GetMachinesInSet(machineSet string) ([]*Machine, error) {
+	var machines []*Machine
+	found := machineSet == ""
+	for i := range c.config.MachineSets {
+		ms := &c.config.MachineSets[i]
+		if machineSet != "" && ms.Name != machineSet {
+			continue
+		}
+		found = true
+		for j := 0; j < ms.Replicas; j++ {
+			machines = append(machines, c.newMachine(ms, j))
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("machineSet not found: %s", machineSet)
+	}
+	return machines, nil
+}
+
+// GetMachinesByPattern returns the machines whose name matches the shell
+// pattern, like "workers-*", as in path.Match.
+func (c *Cluster) GetMachinesByPattern(pattern string) ([]*Machine, error) {
+	var machines []*Machine
+	err := c.forEachMachine(func(m *Machine) error {
+		matched, err := path.Match(pattern, m.machineName)
+		if matched {
+			machines = append(machines, m)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "bad machine name pattern %q", pattern)
+	}
+	if len(machines) < 1 {
+		return nil, fmt.Errorf("%w: no machine name matches %s", ErrMachineNotFound, pattern)
+	}
+	return machines, nil
+}
+
+// GetMachines returns the machines given by machine names, MachineSet names
+// or machine name patterns, without duplicates.
+func (c *Cluster) GetMachines(names []string) ([]*Machine, error) {
+	var machines []*Machine
+	seen := map[string]bool{}
+	for _, name := range names {
+		found, err := c.GetMachineByMachineName(name)
+		matches := []*Machine{found}
+		if err != nil {
+			if matches, err = c.GetMachinesInSet(name); name == "" || err != nil {
+				matches, err = c.GetMachinesByPattern(name)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: no machine, machineSet or machine name pattern matches %s", ErrMachineNotFound, name)
+		}
+		for _, m := range matches {
+			if !seen[m.machineName] {
+				seen[m.machineName] = true
+				machines = append(machines, m)
+			}
+		}
+	}
+	return machines, nil
+}
+
+func (c *Cluster) GetFirstMachine() (*Machine, error) {
 	if len(c.config.MachineSets) == 0 {
 		return nil, errors.New("no machineSet is configured")
 	} else {
 		machineSet := c.config.MachineSets[0]
-		return newMachine(&c.config.Cluster, &machineSet, &machineSet.Spec, 0), nil
+		return c.newMachine(&machineSet, 0), nil
 	}
 }
 
@@ -417,43 +672,87 @@ func mappingFromPort(spec *config.Machine, containerPort int) (*config.PortMappi
 	return nil, fmt.Errorf("unknown containerPort %d", containerPort)
 }
 
-// SSH logs into the named machine with SSH.
-func (c *cluster) SSH(machine *Machine, username string, extraSshArgs string) error {
-	utils.Logger.Infof("SSH into machine [%s] with user [%s]", machine.machineName, username)
+// sshEndpoint picks the host address and port to SSH into from the bindings
+// of the container port 22. Wildcard bindings are reached through localhost.
+func sshEndpoint(bindings []PortBinding) (string, int) {
+	for _, b := range bindings {
+		if b.HostIP != "" && b.HostIP != "0.0.0.0" && b.HostIP != "::" {
+			return b.HostIP, b.HostPort
+		}
+	}
+	return "localhost", bindings[0].HostPort
+}
 
-	hostPort, err := machine.HostPort(22)
-	if err != nil {
-		return err
+// Transports used by SSH to reach the machines.
+const (
+	// SSHViaAuto uses SSH if the machine publishes the port 22, docker exec otherwise.
+	SSHViaAuto = "auto"
+	// SSHViaSSH always uses SSH through the published port 22.
+	SSHViaSSH = "ssh"
+	// SSHViaExec opens a login shell through docker exec.
+	SSHViaExec = "exec"
+)
+
+// SSH logs into the named machine with SSH, or through docker exec depending on
+// the transport. The SSH server gets the SSH timeout to accept the connection,
+// while an SSH session itself isn't bound to the context, unlike a docker exec one.
+func (c *Cluster) SSH(ctx context.Context, machine *Machine, username string, extraSshArgs string, via string) error {
+	machine.logger().Infof("SSH into machine [%s] with user [%s]", machine.machineName, username)
+
+	var bindings []PortBinding
+	switch via {
+	case "", SSHViaAuto, SSHViaSSH:
+		_, err := mappingFromPort(machine.spec, 22)
+		if err == nil {
+			bindings, err = machine.HostPorts(22, "tcp")
+		}
+		if err != nil {
+			if via == SSHViaSSH {
+				return err
+			}
+			machine.logger().Infof("Machine %s has no SSH port published (%v), falling back to docker exec", machine.machineName, err)
+			return c.execSession(ctx, machine, username, extraSshArgs)
+		}
+	case SSHViaExec:
+		return c.execSession(ctx, machine, username, extraSshArgs)
+	default:
+		return fmt.Errorf("unknown ssh transport '%s', expected one of: %s, %s, %s", via, SSHViaAuto, SSHViaSSH, SSHViaExec)
 	}
-	mapping, err := mappingFromPort(machine.spec, 22)
-	if err != nil {
+
+	remote, hostPort := sshEndpoint(bindings)
+	if err := c.refreshKnownHosts(ctx, machine); err != nil {
 		return err
 	}
-	remote := "localhost"
-	if mapping.Address != "" {
-		remote = mapping.Address
-	}
-	path, _ := homedir.Expand(c.config.Cluster.PrivateKey)
+	path := c.privateKeyPath()
 	args := []string{
-		"-o", "UserKnownHostsFile=/dev/null",
-		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=" + c.KnownHostsPath(),
+		"-o", "StrictHostKeyChecking=yes",
 		"-o", "IdentitiesOnly=yes",
 		"-i", path,
+	}
+	if c.caEnabled() {
+		certPath, err := c.userCertificate(username)
+		if err != nil {
+			return err
+		}
+		args = append(args, "-o", "CertificateFile="+certPath)
+	}
+	args = append(args,
 		"-p", f("%d", hostPort),
 		"-l", username,
 		"-t", remote, // https://stackoverflow.com/questions/626533/how-can-i-ssh-directly-to-a-particular-directory
-	}
+	)
 
 	if len(extraSshArgs) > 0 {
 		// if there are any extra SSH args, let's respect them
-		utils.Logger.Infof("With extra SSH args: %s", extraSshArgs)
+		c.logger().Infof("With extra SSH args: %s", extraSshArgs)
 		args = append(args, extraSshArgs)
 	} else {
 		// try to auto cd into currently mapped folder
 		// if bind mount to "/host" exists
 		cd := machine.AutoCdTo()
 		if cd != "" {
-			utils.Logger.Infof("Trying to cd into: %s", cd)
+			c.logger().Infof("Trying to cd into: %s", cd)
 			args = append(args, fmt.Sprintf("cd %s; exec $SHELL -l", cd))
 		}
 	}
@@ -461,28 +760,49 @@ func (c *cluster) SSH(machine *Machine, username string, extraSshArgs string) er
 	// If we ssh in a bit too quickly after the container creation, ssh errors out
 	// with:
 	//   ssh_exchange_identification: read: Connection reset by peer
-	// Let's loop a few times if we receive this message.
-	retries := 25
-	var retry bool
-	for retries > 0 {
-		retry, err = ssh(args)
+	// Let's loop until the SSH timeout if we receive this message.
+	var deadline time.Time
+	if c.timeouts.SSH > 0 {
+		deadline = time.Now().Add(c.timeouts.SSH)
+	}
+	for {
+		retry, err := ssh(args)
 		if !retry {
-			break
+			return err
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return fmt.Errorf("waiting for the SSH server of machine %s timed out after %s: %w", machine.machineName, c.timeouts.SSH, context.DeadlineExceeded)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
 		}
-		retries--
-		time.Sleep(200 * time.Millisecond)
 	}
-
-	return err
 }
 
-// CopyFrom copies files/folders from the machine to the host filesystem
-func (c *cluster) CopyFrom(from *Machine, srcPath, destPath string) error {
-	// CopyTo(hostPath, containerNameOrID, destPath string) error
-	return docker.CopyFrom(from.containerName, srcPath, destPath)
-}
+// execSession opens an interactive login shell of the user in the machine
+// through docker exec, or runs the command in it, without going through SSH.
+// The session is ended when the context is done.
+func (c *Cluster) execSession(ctx context.Context, machine *Machine, username string, command string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !machine.IsStarted() {
+		return fmt.Errorf("machine %s is not running", machine.machineName)
+	}
+	machine.logger().Infof("Opening a session in machine [%s] with user [%s] through docker exec", machine.machineName, username)
 
-// CopyTo copies files/folders from the host filesystem to the machine
-func (c *cluster) CopyTo(srcPath string, to *Machine, destPath string) error {
-	return docker.CopyTo(srcPath, to.containerName, destPath)
+	args := []string{"-c", EXEC_SHELL_SCRIPT, "vind", machine.AutoCdTo()}
+	if command != "" {
+		args = append(args, command)
+	}
+	cmd := machine.runtime.Session(ctx, machine.containerName, username, "/bin/sh", args...)
+	if term := os.Getenv("TERM"); term != "" {
+		cmd.SetEnv("TERM=" + term)
+	}
+	cmd.SetStdin(os.Stdin)
+	cmd.SetStdout(os.Stdout)
+	cmd.SetStderr(os.Stderr)
+	return cmd.Run()
 }
@@ -17,6 +17,8 @@ limitations under the License.
 package cluster
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"testing"
 
@@ -79,6 +81,57 @@ machineSets:
 	assert.Equal(t, "2223:22", args1[i+1])
 }
 
+func TestGetMachinesInSet(t *testing.T) {
+	cluster, err := NewFromYAML([]byte(`
+cluster:
+  name: cluster
+  privateKey: cluster-key
+machineSets:
+- name: masters
+  replicas: 1
+  spec:
+    image: quay.io/brightzheng100/centos7
+    name: node%d
+- name: workers
+  replicas: 2
+  spec:
+    image: quay.io/brightzheng100/centos7
+    name: node%d
+`))
+	assert.NoError(t, err)
+
+	names := func(machines []*Machine) []string {
+		var names []string
+		for _, m := range machines {
+			names = append(names, m.MachineName())
+		}
+		return names
+	}
+	machines, err := cluster.GetMachinesInSet("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"masters-node0", "workers-node0", "workers-node1"}, names(machines))
+
+	machines, err = cluster.GetMachinesInSet("workers")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"workers-node0", "workers-node1"}, names(machines))
+
+	_, err = cluster.GetMachinesInSet("unknown")
+	assert.Error(t, err)
+
+	machines, err = cluster.GetMachinesByPattern("*-node0")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"masters-node0", "workers-node0"}, names(machines))
+
+	machines, err = cluster.GetMachinesByPattern("workers-node1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"workers-node1"}, names(machines))
+
+	_, err = cluster.GetMachinesByPattern("db-*")
+	assert.Error(t, err)
+	_, err = cluster.GetMachinesByPattern("[")
+	assert.Error(t, err)
+}
+
 func indexOf(element string, array []string) int {
 	for k, v := range array {
 		if element == v {
@@ -87,3 +140,59 @@ func indexOf(element string, array []string) int {
 	}
 	return -1 // element not found.
 }
+
+func TestGetMachines(t *testing.T) {
+	cluster, err := NewFromYAML([]byte(`
+cluster:
+  name: cluster
+  privateKey: cluster-key
+machineSets:
+- name: masters
+  replicas: 1
+  spec:
+    image: quay.io/brightzheng100/centos7
+    name: node%d
+- name: workers
+  replicas: 2
+  spec:
+    image: quay.io/brightzheng100/centos7
+    name: node%d
+`))
+	assert.NoError(t, err)
+
+	machine, err := cluster.GetMachineByMachineName("workers-node1")
+	assert.NoError(t, err)
+	assert.Equal(t, "cluster-workers-node1", machine.ContainerName())
+	assert.Equal(t, "workers", machine.MachineSet())
+	assert.Equal(t, 1, machine.Index())
+	assert.Equal(t, "workers-node1.cluster.vind", machine.FQDN())
+
+	_, err = cluster.GetMachineByMachineName("db-node0")
+	assert.True(t, errors.Is(err, ErrMachineNotFound))
+
+	machines, err := cluster.GetMachines([]string{"masters-node0", "workers", "*-node0"})
+	assert.NoError(t, err)
+	assert.Len(t, machines, 3)
+	_, err = cluster.GetMachines([]string{"db"})
+	assert.True(t, errors.Is(err, ErrMachineNotFound))
+
+	// unknown machines fail before anything is done
+	done := 0
+	err = cluster.forSpecificMachines(func(*Machine) error {
+		done++
+		return nil
+	}, []string{"workers-node0", "db-node0"})
+	assert.True(t, errors.Is(err, ErrMachineNotFound))
+	assert.Equal(t, 0, done)
+
+	// loops stop once the context is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	err = cluster.forEachMachine(withContext(ctx, func(*Machine) error {
+		done++
+		cancel()
+		return nil
+	}))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 1, done)
+	assert.True(t, errors.Is(cluster.Exec(ctx, machine, []string{"true"}, ExecOptions{}), context.Canceled))
+}
@@ -0,0 +1,212 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Stdio is the path standing for stdin or stdout in copies, which stream a
+// tar archive then, like "docker cp".
+const Stdio = "-"
+
+// CopyOptions are the options of the copies into machines.
+type CopyOptions struct {
+	// Archive keeps the uid/gid of the copied files, like "docker cp -a".
+	Archive bool
+	// Owner, like "user[:group]", is set on the copied files if not empty.
+	Owner string
+	// Mode, in any form chmod accepts, is set on the copied files if not empty.
+	Mode string
+}
+
+// CopyFrom copies files/folders from the machine to the host filesystem, or
+// streams them as a tar archive to stdout if destPath is "-".
+func (c *Cluster) CopyFrom(ctx context.Context, from *Machine, srcPath, destPath string, opts CopyOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if destPath == Stdio {
+		return from.runtime.CopyArchiveFrom(ctx, from.containerName, srcPath, os.Stdout)
+	}
+	return from.runtime.CopyFrom(ctx, from.containerName, srcPath, destPath, opts.Archive)
+}
+
+// CopyTo copies files/folders from the host filesystem to the machines, in
+// parallel. A tar archive is read from stdin and extracted into the destPath
+// directory if srcPath is "-".
+func (c *Cluster) CopyTo(ctx context.Context, srcPath string, to []*Machine, destPath string, opts CopyOptions) error {
+	if srcPath == Stdio {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return errors.Wrap(err, "read archive from stdin")
+		}
+		roots, err := tarRoots(data)
+		if err != nil {
+			return err
+		}
+		return forMachinesInParallel(to, func(m *Machine) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			m.logger().Infof("Extracting archive into %s:%s ...", m.machineName, destPath)
+			if err := m.runtime.CopyArchiveTo(ctx, bytes.NewReader(data), m.containerName, destPath, opts.Archive); err != nil {
+				return err
+			}
+			return m.setOwnership(ctx, joinAll(destPath, roots), opts)
+		})
+	}
+
+	name := filepath.Base(srcPath)
+	var entries []string
+	if strings.HasSuffix(srcPath, string(filepath.Separator)+".") {
+		// the content of the directory is copied, so only its entries get
+		// the ownership, not the destination directory
+		name = ""
+		dirEntries, err := os.ReadDir(srcPath)
+		if err != nil {
+			return errors.Wrap(err, "read source directory")
+		}
+		for _, e := range dirEntries {
+			entries = append(entries, e.Name())
+		}
+	}
+	return forMachinesInParallel(to, func(m *Machine) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m.logger().Infof("Copying %s to %s:%s ...", srcPath, m.machineName, destPath)
+		targets := []string{destPath}
+		if name == "" {
+			targets = joinAll(destPath, entries)
+		} else if m.isDir(ctx, destPath) {
+			targets = []string{path.Join(destPath, name)}
+		}
+		if err := m.runtime.CopyTo(ctx, srcPath, m.containerName, destPath, opts.Archive); err != nil {
+			return err
+		}
+		return m.setOwnership(ctx, targets, opts)
+	})
+}
+
+// Copy copies files/folders from a machine to other machines, in parallel,
+// through a tar archive held in memory.
+func (c *Cluster) Copy(ctx context.Context, from *Machine, srcPath string, to []*Machine, destPath string, opts CopyOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var archive bytes.Buffer
+	if err := from.runtime.CopyArchiveFrom(ctx, from.containerName, srcPath, &archive); err != nil {
+		return err
+	}
+	roots, err := tarRoots(archive.Bytes())
+	if err != nil {
+		return err
+	}
+	return forMachinesInParallel(to, func(m *Machine) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m.logger().Infof("Copying %s:%s to %s:%s ...", from.machineName, srcPath, m.machineName, destPath)
+		dir, data, names := destPath, archive.Bytes(), roots
+		if len(roots) == 1 && !m.isDir(ctx, destPath) {
+			// like cp, the copy is named after destPath if it's not a directory
+			dir, names = path.Dir(destPath), []string{path.Base(destPath)}
+			renamed, err := renameTarRoot(data, roots[0], names[0])
+			if err != nil {
+				return err
+			}
+			data = renamed
+		}
+		if err := m.runtime.CopyArchiveTo(ctx, bytes.NewReader(data), m.containerName, dir, opts.Archive); err != nil {
+			return err
+		}
+		return m.setOwnership(ctx, joinAll(dir, names), opts)
+	})
+}
+
+// isDir returns whether the path is a directory in the machine.
+func (m *Machine) isDir(ctx context.Context, path string) bool {
+	return m.runtime.Cmder(ctx, m.containerName).Command("test", "-d", path).Run() == nil
+}
+
+// setOwnership sets the owner and mode of the options on the paths, and all
+// the files under them.
+func (m *Machine) setOwnership(ctx context.Context, paths []string, opts CopyOptions) error {
+	if len(paths) < 1 {
+		return nil
+	}
+	if opts.Owner != "" {
+		if err := m.run(ctx, "chown", append([]string{"-R", opts.Owner}, paths...)...); err != nil {
+			return errors.Wrapf(err, "can't change owner of %v on %s", paths, m.machineName)
+		}
+	}
+	if opts.Mode != "" {
+		if err := m.run(ctx, "chmod", append([]string{"-R", opts.Mode}, paths...)...); err != nil {
+			return errors.Wrapf(err, "can't change mode of %v on %s", paths, m.machineName)
+		}
+	}
+	return nil
+}
+
+// forMachinesInParallel runs do for all the machines at once, returning the
+// errors of all the failed ones.
+func forMachinesInParallel(machines []*Machine, do func(*Machine) error) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(machines))
+	for i, m := range machines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = do(m)
+		}()
+	}
+	wg.Wait()
+
+	if len(machines) == 1 {
+		return errs[0]
+	}
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			machines[i].logger().Errorf("Machine %s: %v", machines[i].machineName, err)
+			failed = append(failed, machines[i].machineName)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed on machines: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// joinAll joins each of the names to the dir.
+func joinAll(dir string, names []string) []string {
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = path.Join(dir, name)
+	}
+	return paths
+}
@@ -0,0 +1,42 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyToDirectoryContent(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newFakeCluster(t)
+	assert.NoError(t, c.Create(ctx, CreateOptions{}))
+	m, err := c.GetMachineByMachineName("nodes-node0")
+	assert.NoError(t, err)
+
+	src := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "a.conf"), nil, 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(src, "b.d"), 0755))
+
+	opts := CopyOptions{Owner: "alice"}
+	assert.NoError(t, c.CopyTo(ctx, src+string(filepath.Separator)+".", []*Machine{m}, "/etc", opts))
+	execs := fake.Container("fake-nodes-node0").Execs
+	assert.Equal(t, []string{"chown", "-R", "alice", "/etc/a.conf", "/etc/b.d"}, execs[len(execs)-1])
+}
@@ -0,0 +1,208 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/docker"
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/brightzheng100/vind/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+// emptyDocker answers the docker queries like a docker without any image nor
+// container.
+type emptyDocker struct{}
+
+func (d emptyDocker) Command(name string, args ...string) exec.Cmd {
+	return d.CommandContext(context.Background(), name, args...)
+}
+
+func (emptyDocker) CommandContext(ctx context.Context, name string, args ...string) exec.Cmd {
+	return &emptyDockerCmd{missing: slices.Contains(args, "inspect")}
+}
+
+type emptyDockerCmd struct {
+	missing bool
+	stderr  io.Writer
+}
+
+func (cmd *emptyDockerCmd) Run() error {
+	if !cmd.missing {
+		return nil
+	}
+	if cmd.stderr != nil {
+		io.WriteString(cmd.stderr, "Error: No such object\n")
+	}
+	return errors.New("exit status 1")
+}
+
+func (cmd *emptyDockerCmd) SetEnv(...string)      {}
+func (cmd *emptyDockerCmd) SetStdin(io.Reader)    {}
+func (cmd *emptyDockerCmd) SetStdout(io.Writer)   {}
+func (cmd *emptyDockerCmd) SetStderr(w io.Writer) { cmd.stderr = w }
+
+func TestCreateDryRun(t *testing.T) {
+	c, _ := newFakeCluster(t)
+	c.SetRuntime(runtime.Docker{}).SetDryRun(true)
+	recorder := &exec.Recorder{Cmder: emptyDocker{}, Passthrough: docker.IsQuery}
+	defaultCmder := exec.DefaultCmder
+	exec.DefaultCmder = recorder
+	t.Cleanup(func() { exec.DefaultCmder = defaultCmder })
+
+	assert.NoError(t, c.Create(context.Background(), CreateOptions{}))
+
+	var lines []string
+	for _, command := range recorder.Commands() {
+		assert.Equal(t, "docker", command.Name)
+		assert.False(t, docker.IsQuery(command.Name, command.Args), "%s", command)
+		lines = append(lines, command.String())
+	}
+	if assert.NotEmpty(t, lines) {
+		assert.Equal(t, "docker pull quay.io/brightzheng100/ubuntu22.04", lines[0])
+	}
+	for _, name := range []string{"fake-nodes-node0", "fake-nodes-node1"} {
+		assert.True(t, slices.ContainsFunc(lines, func(line string) bool {
+			return strings.HasPrefix(line, "docker create ") && strings.Contains(line, "--name "+name)
+		}), "no creation of %s in %v", name, lines)
+		assert.Contains(t, lines, "docker start "+name)
+	}
+	// nothing ran, so no host key could be recorded
+	_, err := os.Stat(c.KnownHostsPath())
+	assert.True(t, os.IsNotExist(err))
+
+	var script bytes.Buffer
+	assert.NoError(t, exec.WriteScript(&script, "Generated by a test", recorder.Commands()))
+	publicKey, err := os.ReadFile(c.privateKeyPath() + ".pub")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(script.String(), "#!/bin/sh\n# Generated by a test\nset -e\n\ndocker pull "), script.String())
+	assert.Contains(t, script.String(), "\ndocker start fake-nodes-node1\n")
+	assert.Contains(t, script.String(), "<<'VIND_EOF'\n"+string(publicKey))
+}
+
+func TestWriteScript(t *testing.T) {
+	var script bytes.Buffer
+	assert.NoError(t, exec.WriteScript(&script, "", []exec.Recorded{
+		{Name: "docker", Args: []string{"exec", "node", "sh", "-c", "echo 'hi' $HOME"}},
+		{Name: "docker", Args: []string{"exec", "-i", "node", "tee", "/a"}, Stdin: []byte("a\nb\n")},
+		{Name: "docker", Args: []string{"exec", "-i", "node", "tee", "/b"}, Stdin: []byte("no newline")},
+		{Name: "docker", Args: []string{"exec", "-i", "node", "true"}, Stdin: []byte{}},
+	}))
+	assert.Equal(t, `#!/bin/sh
+set -e
+
+docker exec node sh -c 'echo '\''hi'\'' $HOME'
+
+docker exec -i node tee /a <<'VIND_EOF'
+a
+b
+VIND_EOF
+
+printf '%s' bm8gbmV3bGluZQ== | base64 -d | docker exec -i node tee /b
+
+docker exec -i node true </dev/null
+`, script.String())
+}
+
+func TestRotateSSHKeyDryRun(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newFakeCluster(t)
+	assert.NoError(t, c.Create(ctx, CreateOptions{}))
+	private, err := os.ReadFile(c.privateKeyPath())
+	assert.NoError(t, err)
+	public, err := os.ReadFile(c.privateKeyPath() + ".pub")
+	assert.NoError(t, err)
+
+	var inputs []string
+	onExec := fake.OnExec
+	fake.OnExec = func(container string, command []string, stdin []byte) ([]byte, error) {
+		inputs = append(inputs, string(stdin))
+		return onExec(container, command, stdin)
+	}
+	assert.NoError(t, c.SetDryRun(true).RotateSSHKey(ctx))
+
+	// the key pair is left alone
+	data, err := os.ReadFile(c.privateKeyPath())
+	assert.NoError(t, err)
+	assert.Equal(t, private, data)
+	data, err = os.ReadFile(c.privateKeyPath() + ".pub")
+	assert.NoError(t, err)
+	assert.Equal(t, public, data)
+	_, err = os.Stat(c.privateKeyPath() + ".old")
+	assert.True(t, os.IsNotExist(err), "%v", err)
+
+	// while the machines are given the new key
+	authorized := slices.ContainsFunc(inputs, func(input string) bool {
+		return strings.Contains(input, "fake@vind.mail") && !strings.Contains(input, string(public))
+	})
+	assert.True(t, authorized, "%q", inputs)
+}
+
+func TestDeleteDryRun(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newFakeCluster(t)
+	assert.NoError(t, c.Create(ctx, CreateOptions{}))
+	assert.NoError(t, c.updateState(func(s *State) {
+		s.Partitions = append(s.Partitions, Partition{Groups: [][]string{{"nodes-node0"}, {"nodes-node1"}}})
+	}))
+	knownHosts, err := os.ReadFile(c.KnownHostsPath())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, knownHosts)
+	state, err := os.ReadFile(c.statePath())
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.SetDryRun(true).Delete(ctx))
+
+	data, err := os.ReadFile(c.KnownHostsPath())
+	assert.NoError(t, err)
+	assert.Equal(t, knownHosts, data)
+	data, err = os.ReadFile(c.statePath())
+	assert.NoError(t, err)
+	assert.Equal(t, state, data)
+}
+
+func TestLockDryRun(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newFakeCluster(t)
+
+	unlock, err := c.SetDryRun(true).Lock(ctx, 0)
+	assert.NoError(t, err)
+	unlock()
+	_, err = os.Stat(c.Dir())
+	assert.True(t, os.IsNotExist(err), "%v", err)
+
+	// a held lock is still honoured
+	unlock, err = c.SetDryRun(false).Lock(ctx, 0)
+	assert.NoError(t, err)
+	_, err = c.SetDryRun(true).Lock(ctx, 0)
+	assert.True(t, errors.Is(err, ErrLocked), "%v", err)
+	unlock()
+
+	unlock, err = c.Lock(ctx, 0)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(c.LockPath())
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+	unlock()
+}
@@ -0,0 +1,37 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"errors"
+)
+
+// The errors are wrapped with fmt.Errorf and "%w", so that errors.Is finds
+// them, which the errors of github.com/pkg/errors don't allow.
+var (
+	// ErrMachineNotFound is returned for a machine name which isn't in the
+	// configuration of the cluster.
+	ErrMachineNotFound = errors.New("machine not found")
+	// ErrNotCreated is returned for operations needing a machine which
+	// hasn't been created.
+	ErrNotCreated = errors.New("machine not created")
+	// ErrNotStarted is returned for operations needing a machine which is
+	// stopped.
+	ErrNotStarted = errors.New("machine not started")
+	// ErrLocked is returned when another process holds the lock of the
+	// cluster.
+	ErrLocked = errors.New("cluster locked")
+)
@@ -0,0 +1,154 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/docker"
+)
+
+// The types of the events of the machines.
+const (
+	EventCreated       = "created"
+	EventStarted       = "started"
+	EventStopped       = "stopped"
+	EventDied          = "died"
+	EventOOM           = "oom"
+	EventHealthChanged = "health_changed"
+	EventRemoved       = "removed"
+)
+
+// MachineEvent is an event of a machine of the cluster, normalized from the
+// events of its container.
+type MachineEvent struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	Cluster    string    `json:"cluster"`
+	MachineSet string    `json:"machineSet"`
+	Machine    string    `json:"machine"`
+	Container  string    `json:"container"`
+	// ExitCode is the exit code of a machine which died.
+	ExitCode *int `json:"exitCode,omitempty"`
+	// Health is the new health status of the machine, like "unhealthy".
+	Health string `json:"health,omitempty"`
+}
+
+// String formats the event as a line, like
+// "2025-01-06T10:00:00Z test-node0 died exitCode=137".
+func (e MachineEvent) String() string {
+	line := f("%s %s %s", e.Time.Format(time.RFC3339), e.Machine, e.Type)
+	if e.ExitCode != nil {
+		line += f(" exitCode=%d", *e.ExitCode)
+	}
+	if e.Health != "" {
+		line += f(" health=%s", e.Health)
+	}
+	return line
+}
+
+// Events streams the events of the machines of the cluster, or of the named
+// ones only, until the context is done: their creation, start, stop, death,
+// OOM kill, health changes and removal. The error channel gets the error
+// ending the stream early, if any, then both channels are closed. It fails
+// with ErrMachineNotFound for a name which isn't a machine of the cluster.
+func (c *Cluster) Events(ctx context.Context, machineNames []string) (<-chan MachineEvent, <-chan error) {
+	events := make(chan MachineEvent)
+	errs := make(chan error, 1)
+	if err := c.checkMachineNames(machineNames); err != nil {
+		errs <- err
+		close(events)
+		close(errs)
+		return events, errs
+	}
+
+	containerEvents, containerErrs := c.runtime.Events(ctx, "creator=vind", "cluster="+c.Name())
+	go func() {
+		defer close(errs)
+		defer close(events)
+		for containerEvent := range containerEvents {
+			event, ok := c.machineEvent(containerEvent)
+			if !ok || (len(machineNames) > 0 && !slices.Contains(machineNames, event.Machine)) {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := <-containerErrs; err != nil {
+			errs <- err
+		}
+	}()
+	return events, errs
+}
+
+// machineEvent normalizes the event of a container of the cluster, returning
+// false for the events which aren't about the life of the machine, like
+// "exec_start".
+func (c *Cluster) machineEvent(event docker.ContainerEvent) (MachineEvent, bool) {
+	e := MachineEvent{
+		Time:       event.Time,
+		Cluster:    c.Name(),
+		MachineSet: event.Attributes["machineSet"],
+		Machine:    c.machineNameOf(event),
+		Container:  event.Container,
+	}
+	switch action := event.Action; {
+	case action == "create":
+		e.Type = EventCreated
+	case action == "start":
+		e.Type = EventStarted
+	case action == "stop":
+		e.Type = EventStopped
+	case action == "die":
+		e.Type = EventDied
+		if code, err := strconv.Atoi(event.Attributes["exitCode"]); err == nil {
+			e.ExitCode = &code
+		}
+	case action == "oom":
+		e.Type = EventOOM
+	case strings.HasPrefix(action, "health_status:"):
+		e.Type = EventHealthChanged
+		e.Health = strings.TrimSpace(strings.TrimPrefix(action, "health_status:"))
+	case action == "destroy":
+		e.Type = EventRemoved
+	default:
+		return e, false
+	}
+	return e, true
+}
+
+// machineNameOf returns the name of the machine of the container, from its
+// machineSet and index labels, or from the container name for a machine which
+// isn't in the configuration anymore.
+func (c *Cluster) machineNameOf(event docker.ContainerEvent) string {
+	index, err := strconv.Atoi(event.Attributes["index"])
+	if err == nil {
+		for i := range c.config.MachineSets {
+			ms := &c.config.MachineSets[i]
+			if ms.Name == event.Attributes["machineSet"] && index < ms.Replicas {
+				return c.newMachine(ms, index).machineName
+			}
+		}
+	}
+	return strings.TrimPrefix(event.Container, c.Name()+"-")
+}
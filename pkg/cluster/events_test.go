@@ -0,0 +1,95 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// nextEvents reads n events, failing the test if they don't come.
+func nextEvents(t *testing.T, events <-chan MachineEvent, n int) []MachineEvent {
+	var received []MachineEvent
+	for len(received) < n {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("events closed after %v", received)
+			}
+			received = append(received, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no event after %v", received)
+		}
+	}
+	return received
+}
+
+func TestEvents(t *testing.T) {
+	c, fake := newFakeCluster(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	all, _ := c.Events(ctx, nil)
+	node1, node1Errs := c.Events(ctx, []string{"nodes-node1"})
+
+	assert.NoError(t, c.Create(ctx, CreateOptions{}))
+	var types []string
+	for _, event := range nextEvents(t, all, 4) {
+		assert.Equal(t, "fake", event.Cluster)
+		assert.Equal(t, "nodes", event.MachineSet)
+		types = append(types, event.Machine+" "+event.Type)
+	}
+	assert.Equal(t, []string{"nodes-node0 created", "nodes-node0 started", "nodes-node1 created", "nodes-node1 started"}, types)
+
+	assert.NoError(t, fake.Emit("fake-nodes-node1", "exec_start: sh", nil))
+	assert.NoError(t, fake.Emit("fake-nodes-node1", "oom", nil))
+	assert.NoError(t, fake.Emit("fake-nodes-node1", "die", map[string]string{"exitCode": "137"}))
+	assert.NoError(t, fake.Emit("fake-nodes-node1", "health_status: unhealthy", nil))
+	events := nextEvents(t, node1, 5)
+	assert.Equal(t, EventCreated, events[0].Type)
+	assert.Equal(t, EventStarted, events[1].Type)
+	assert.Equal(t, EventOOM, events[2].Type)
+	assert.Equal(t, EventDied, events[3].Type)
+	if assert.NotNil(t, events[3].ExitCode) {
+		assert.Equal(t, 137, *events[3].ExitCode)
+	}
+	assert.Equal(t, "fake-nodes-node1", events[3].Container)
+	assert.Contains(t, events[3].String(), " nodes-node1 died exitCode=137")
+	assert.Equal(t, EventHealthChanged, events[4].Type)
+	assert.Equal(t, "unhealthy", events[4].Health)
+
+	assert.NoError(t, c.Delete(ctx))
+	events = nextEvents(t, node1, 2)
+	assert.Equal(t, EventDied, events[0].Type)
+	assert.Equal(t, EventRemoved, events[1].Type)
+
+	cancel()
+	for range node1 {
+	}
+	assert.NoError(t, <-node1Errs)
+}
+
+func TestEventsUnknownMachine(t *testing.T) {
+	c, _ := newFakeCluster(t)
+	events, errs := c.Events(context.Background(), []string{"nodes-node7"})
+	_, ok := <-events
+	assert.False(t, ok)
+	err := <-errs
+	assert.True(t, errors.Is(err, ErrMachineNotFound), "%v", err)
+}
@@ -0,0 +1,241 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/brightzheng100/vind/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeCluster(t *testing.T) (*Cluster, *runtime.Fake) {
+	return newFakeClusterFromYAML(t, `
+cluster:
+  name: fake
+  privateKey: cluster-key
+  keyType: ed25519
+machineSets:
+- name: nodes
+  replicas: 2
+  spec:
+    image: quay.io/brightzheng100/ubuntu22.04
+    name: node%d
+    portMappings:
+    - containerPort: 22
+`)
+}
+
+// newFakeClusterFromYAML creates a cluster on the Fake runtime, whose machines
+// have a host key.
+func newFakeClusterFromYAML(t *testing.T, yaml string) (*Cluster, *runtime.Fake) {
+	t.Setenv("VIND_HOME", t.TempDir())
+	c, err := NewFromYAML([]byte(yaml))
+	assert.NoError(t, err)
+	_, hostKey, err := generateKey(config.KeyTypeED25519, "")
+	assert.NoError(t, err)
+	fake := runtime.NewFake()
+	fake.OnExec = func(container string, command []string, stdin []byte) ([]byte, error) {
+		if len(command) == 3 && command[2] == HOST_KEYS_SCRIPT {
+			return hostKey, nil
+		}
+		return nil, nil
+	}
+	return c.SetRuntime(fake), fake
+}
+
+func TestFakeRuntimeLifecycle(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newFakeCluster(t)
+
+	assert.NoError(t, c.Create(ctx, CreateOptions{}))
+	assert.Equal(t, []string{"quay.io/brightzheng100/ubuntu22.04"}, fake.Pulled())
+	assert.Len(t, fake.Containers(), 2)
+	node0 := fake.Container("fake-nodes-node0")
+	if assert.NotNil(t, node0) {
+		assert.True(t, node0.Running)
+		assert.Equal(t, "nodes-node0", node0.Hostname)
+		assert.Equal(t, "fake", node0.Labels["cluster"])
+	}
+
+	machines, err := c.Show(ctx, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, machines, 2) {
+		assert.Equal(t, "nodes-node0", machines[0].MachineName())
+		assert.True(t, machines[0].IsStarted())
+		port, err := machines[0].HostPort(22)
+		assert.NoError(t, err)
+		assert.True(t, port >= 32768, "%d", port)
+		assert.NotEmpty(t, machines[0].IP())
+	}
+
+	var table bytes.Buffer
+	assert.NoError(t, new(TableFormatter).Format(&table, c, machines))
+	assert.Equal(t, `CONTAINER NAME     MACHINE NAME   PORTS       IP           IMAGE                                CMD          STATE
+fake-nodes-node0   nodes-node0    32768->22   172.17.0.2   quay.io/brightzheng100/ubuntu22.04   /sbin/init   Running
+fake-nodes-node1   nodes-node1    32769->22   172.17.0.3   quay.io/brightzheng100/ubuntu22.04   /sbin/init   Running
+`, table.String())
+
+	var js bytes.Buffer
+	assert.NoError(t, new(JSONFormatter).Format(&js, c, machines))
+	var shown struct {
+		Machines []map[string]interface{} `json:"machines"`
+	}
+	assert.NoError(t, json.Unmarshal(js.Bytes(), &shown))
+	assert.Len(t, shown.Machines, 2)
+
+	var inventory bytes.Buffer
+	assert.NoError(t, new(AnsibleFormatter).Format(&inventory, c, machines))
+	assert.Contains(t, inventory.String(), "ansible_port: 32769\n")
+
+	var sshConfig bytes.Buffer
+	assert.NoError(t, new(SSHConfigFormatter).Format(&sshConfig, c, machines))
+	expected := ""
+	for i, port := range []int{32768, 32769} {
+		expected += f(`Host nodes-node%d
+    Hostname localhost
+    Port %d
+    User root
+    IdentityFile %s
+    UserKnownHostsFile %s
+    StrictHostKeyChecking yes
+`, i, port, c.privateKeyPath(), c.KnownHostsPath())
+	}
+	assert.Equal(t, expected, sshConfig.String())
+
+	assert.NoError(t, c.Stop(ctx, []string{"nodes-node1"}))
+	assert.False(t, fake.Container("fake-nodes-node1").Running)
+	assert.True(t, fake.Container("fake-nodes-node0").Running)
+	assert.NoError(t, c.Start(ctx, []string{"nodes-node1"}))
+	assert.True(t, fake.Container("fake-nodes-node1").Running)
+
+	assert.NoError(t, c.Delete(ctx))
+	assert.Empty(t, fake.Containers())
+}
+
+func TestFakeRuntimeErrors(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newFakeCluster(t)
+
+	err := c.Start(ctx, []string{"nodes-node0"})
+	assert.True(t, errors.Is(err, ErrNotCreated), "%v", err)
+	err = c.Start(ctx, []string{"nodes-node9"})
+	assert.True(t, errors.Is(err, ErrMachineNotFound), "%v", err)
+
+	assert.NoError(t, c.Create(ctx, CreateOptions{}))
+	assert.NoError(t, c.Stop(ctx, []string{"nodes-node0"}))
+	m, err := c.GetMachineByMachineName("nodes-node0")
+	assert.NoError(t, err)
+	err = c.Exec(ctx, m, []string{"true"}, ExecOptions{})
+	assert.True(t, errors.Is(err, ErrNotStarted), "%v", err)
+
+	fake.Down = true
+	assert.Error(t, c.Create(ctx, CreateOptions{}))
+	assert.Len(t, fake.Containers(), 2)
+}
+
+func TestFakeRuntimeLoginUser(t *testing.T) {
+	c, fake := newFakeClusterFromYAML(t, `
+cluster:
+  name: fake
+  privateKey: cluster-key
+machineSets:
+- name: nodes
+  replicas: 1
+  spec:
+    image: quay.io/brightzheng100/ubuntu22.04
+    name: node%d
+    user: ubuntu
+`)
+	assert.NoError(t, c.Create(context.Background(), CreateOptions{}))
+
+	var scripts []string
+	for _, command := range fake.Container("fake-nodes-node0").Execs {
+		scripts = append(scripts, command[len(command)-1])
+	}
+	assert.Contains(t, scripts, f(LOGIN_USER_SCRIPT, "ubuntu", defaultShell))
+	assert.Contains(t, scripts, f(INIT_SCRIPT, "ubuntu"))
+}
+
+func TestFakeRuntimeExecSession(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, fake := newFakeCluster(t)
+	assert.NoError(t, c.Create(ctx, CreateOptions{}))
+	m, err := c.GetMachineByMachineName("nodes-node0")
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.SSH(ctx, m, "root", "hostname", SSHViaExec))
+	execs := fake.Container("fake-nodes-node0").Execs
+	assert.Equal(t, []string{"/bin/sh", "-c", EXEC_SHELL_SCRIPT, "vind", "", "hostname"}, execs[len(execs)-1])
+
+	cancel()
+	err = c.SSH(ctx, m, "root", "hostname", SSHViaExec)
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+}
+
+func TestFakeRuntimeRotateSSHKeyStopped(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newFakeCluster(t)
+	assert.NoError(t, c.Create(ctx, CreateOptions{}))
+	assert.NoError(t, c.Stop(ctx, []string{"nodes-node1"}))
+
+	var inputs []string
+	onExec := fake.OnExec
+	fake.OnExec = func(container string, command []string, stdin []byte) ([]byte, error) {
+		if container == "fake-nodes-node1" {
+			inputs = append(inputs, string(stdin))
+		}
+		return onExec(container, command, stdin)
+	}
+	assert.NoError(t, c.RotateSSHKey(ctx))
+	state, err := c.State()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nodes-node1"}, state.StaleKeys)
+	// the .old key is the only one nodes-node1 authorizes
+	assert.Error(t, c.RotateSSHKey(ctx))
+
+	assert.NoError(t, c.Start(ctx, []string{"nodes-node1"}))
+	public, err := os.ReadFile(c.privateKeyPath() + ".pub")
+	assert.NoError(t, err)
+	assert.Contains(t, inputs, string(public))
+	state, err = c.State()
+	assert.NoError(t, err)
+	assert.Empty(t, state.StaleKeys)
+	assert.NoError(t, c.RotateSSHKey(ctx))
+}
+
+func TestReplaceKeyRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	assert.NoError(t, writeKey(path, []byte("private"), []byte("public")))
+	// the public key can't be backed up
+	assert.NoError(t, os.MkdirAll(filepath.Join(path+".pub.old", "dir"), 0700))
+
+	assert.Error(t, replaceKey(path, []byte("new private"), []byte("new public")))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "private", string(data))
+	data, err = os.ReadFile(path + ".pub")
+	assert.NoError(t, err)
+	assert.Equal(t, "public", string(data))
+}
@@ -0,0 +1,131 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The results of the operations recorded in the history.
+const (
+	HistorySucceeded   = "succeeded"
+	HistoryFailed      = "failed"
+	HistoryInterrupted = "interrupted"
+)
+
+// HistoryEntry is an operation which changed the cluster, as recorded in its
+// history.
+type HistoryEntry struct {
+	Time time.Time `json:"time"`
+	User string    `json:"user"`
+	// Command is the vind command, like "create" or "keys rotate".
+	Command string `json:"command"`
+	// Args are the arguments of vind, flags included.
+	Args []string `json:"args,omitempty"`
+	// Machines are the machines the operation was about.
+	Machines []string `json:"machines,omitempty"`
+	Result   string   `json:"result"`
+	Error    string   `json:"error,omitempty"`
+	// Duration is how long the operation took, in seconds.
+	Duration float64 `json:"duration"`
+}
+
+// HistoryFilter selects entries of the history, the zero value selecting
+// them all.
+type HistoryFilter struct {
+	Command string
+	User    string
+	Machine string
+	Result  string
+	Since   time.Time
+	// Last only keeps the last entries, once filtered.
+	Last int
+}
+
+func (filter HistoryFilter) matches(entry *HistoryEntry) bool {
+	return (filter.Command == "" || entry.Command == filter.Command) &&
+		(filter.User == "" || entry.User == filter.User) &&
+		(filter.Machine == "" || slices.Contains(entry.Machines, filter.Machine)) &&
+		(filter.Result == "" || entry.Result == filter.Result) &&
+		!entry.Time.Before(filter.Since)
+}
+
+// HistoryPath returns the path of the history file of the cluster, one JSON
+// entry per line.
+func (c *Cluster) HistoryPath() string {
+	return filepath.Join(c.Dir(), "history.jsonl")
+}
+
+// RecordHistory appends the entry to the history of the cluster.
+func (c *Cluster) RecordHistory(entry HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir(), 0700); err != nil {
+		return errors.Wrap(err, "history: create directory")
+	}
+	file, err := os.OpenFile(c.HistoryPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Wrap(err, "history: open")
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return errors.Wrap(err, "history: write")
+	}
+	return nil
+}
+
+// History returns the entries of the history of the cluster selected by the
+// filter, oldest first. The lines which can't be parsed are skipped.
+func (c *Cluster) History(filter HistoryFilter) ([]HistoryEntry, error) {
+	file, err := os.Open(c.HistoryPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "history: open")
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			c.logger().Debugf("Skipping line %d of %s: %v", line, c.HistoryPath(), err)
+			continue
+		}
+		if filter.matches(&entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "history: read")
+	}
+	if filter.Last > 0 && len(entries) > filter.Last {
+		entries = entries[len(entries)-filter.Last:]
+	}
+	return entries, nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	c, _ := newFakeCluster(t)
+
+	entries, err := c.History(HistoryFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	start := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	recorded := []HistoryEntry{
+		{Time: start, User: "alice", Command: "create", Machines: []string{"nodes-node0", "nodes-node1"}, Result: HistorySucceeded, Duration: 4.2},
+		{Time: start.Add(time.Hour), User: "bob", Command: "stop", Args: []string{"stop", "nodes-node1"}, Machines: []string{"nodes-node1"}, Result: HistoryFailed, Error: "boom", Duration: 0.5},
+		{Time: start.Add(2 * time.Hour), User: "alice", Command: "delete", Machines: []string{"nodes-node0", "nodes-node1"}, Result: HistoryInterrupted, Duration: 1},
+	}
+	for _, entry := range recorded {
+		assert.NoError(t, c.RecordHistory(entry))
+	}
+	// a torn line is skipped
+	file, err := os.OpenFile(c.HistoryPath(), os.O_WRONLY|os.O_APPEND, 0)
+	if assert.NoError(t, err) {
+		file.WriteString("{\"time\":\n")
+		file.Close()
+	}
+
+	entries, err = c.History(HistoryFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, len(recorded), len(entries))
+	for i := range recorded {
+		assert.True(t, recorded[i].Time.Equal(entries[i].Time))
+		entries[i].Time = recorded[i].Time
+	}
+	assert.Equal(t, recorded, entries)
+
+	commands := func(filter HistoryFilter) []string {
+		entries, err := c.History(filter)
+		assert.NoError(t, err)
+		var commands []string
+		for _, entry := range entries {
+			commands = append(commands, entry.Command)
+		}
+		return commands
+	}
+	assert.Equal(t, []string{"create", "delete"}, commands(HistoryFilter{User: "alice"}))
+	assert.Equal(t, []string{"stop"}, commands(HistoryFilter{Command: "stop"}))
+	assert.Equal(t, []string{"create", "stop", "delete"}, commands(HistoryFilter{Machine: "nodes-node1"}))
+	assert.Equal(t, []string{"stop"}, commands(HistoryFilter{Result: HistoryFailed}))
+	assert.Equal(t, []string{"stop", "delete"}, commands(HistoryFilter{Since: start.Add(time.Hour)}))
+	assert.Equal(t, []string{"delete"}, commands(HistoryFilter{User: "alice", Last: 1}))
+	assert.Empty(t, commands(HistoryFilter{Machine: "nodes-node2"}))
+}
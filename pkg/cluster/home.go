@@ -0,0 +1,68 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// HomeEnv is the environment variable overriding the directory where vind
+// keeps its own data, like the cluster SSH keys.
+const HomeEnv = "VIND_HOME"
+
+// defaultHome is the default directory where vind keeps its own data.
+const defaultHome = "~/.vind"
+
+// Home returns the directory where vind keeps its own data.
+func Home() string {
+	home := os.Getenv(HomeEnv)
+	if home == "" {
+		home = defaultHome
+	}
+	path, err := homedir.Expand(home)
+	if err != nil {
+		return home
+	}
+	return path
+}
+
+// Dir returns the directory where vind keeps the data of this cluster.
+func (c *Cluster) Dir() string {
+	return filepath.Join(Home(), "clusters", c.Name())
+}
+
+// privateKeyPath resolves the cluster private key. A plain key name, like
+// "cluster-key", is kept in the cluster directory while a path is used as is.
+// A key that already exists relatively to the working directory, as created by
+// earlier versions of vind, is still honoured.
+func (c *Cluster) privateKeyPath() string {
+	key := c.config.Cluster.PrivateKey
+	if key == "" {
+		return ""
+	}
+	path, err := homedir.Expand(key)
+	if err != nil {
+		path = key
+	}
+	if filepath.IsAbs(path) || strings.ContainsAny(key, "/"+string(filepath.Separator)) || fileExists(path) {
+		return path
+	}
+	return filepath.Join(c.Dir(), key)
+}
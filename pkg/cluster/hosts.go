@@ -0,0 +1,214 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HOSTS_FILE_PATH is the hosts file of the machines and of Unix hosts.
+const HOSTS_FILE_PATH = "/etc/hosts"
+
+// HOSTS_WRITE_SCRIPT overwrites /etc/hosts with stdin. Docker bind mounts the
+// file, so it can't be replaced, only written in place.
+const HOSTS_WRITE_SCRIPT = `cat > /etc/hosts`
+
+// DefaultHostsFile returns the path of the hosts file of the host.
+func DefaultHostsFile() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("SystemRoot"), "System32", "drivers", "etc", "hosts")
+	}
+	return HOSTS_FILE_PATH
+}
+
+// HostEntry is a line of a hosts file.
+type HostEntry struct {
+	IP    string
+	Names []string
+}
+
+// String formats the entry as a hosts file line.
+func (e HostEntry) String() string {
+	return e.IP + "\t" + strings.Join(e.Names, " ")
+}
+
+// hostsMarker identifies the block of the cluster in hosts files.
+func (c *Cluster) hostsMarker() string {
+	return "vind " + c.Name()
+}
+
+// runningMachines returns the running machines of the cluster, with their
+// networks.
+func (c *Cluster) runningMachines() ([]*Machine, error) {
+	var machines []*Machine
+	err := c.forEachMachine(func(m *Machine) error {
+		if !m.IsStarted() {
+			return nil
+		}
+		if _, err := m.networks(); err != nil {
+			return err
+		}
+		machines = append(machines, m)
+		return nil
+	})
+	return machines, err
+}
+
+// hostEntries returns the hosts entries of the machines, as seen from the
+// target machine, or from the host if target is nil. A machine is reached
+// by its IP in a network shared with the target, or else in its first one.
+func hostEntries(target *Machine, machines []*Machine) []HostEntry {
+	shared := map[string]bool{}
+	if target != nil {
+		for _, network := range target.runtimeNetworks {
+			shared[network.Name] = true
+		}
+	}
+	var entries []HostEntry
+	for _, m := range machines {
+		ip := ""
+		for _, network := range m.runtimeNetworks {
+			if network.IP == "" {
+				continue
+			}
+			if ip == "" || shared[network.Name] {
+				ip = network.IP
+			}
+			if shared[network.Name] {
+				break
+			}
+		}
+		if ip == "" {
+			continue
+		}
+		entries = append(entries, HostEntry{IP: ip, Names: []string{m.fqdn, m.machineName}})
+	}
+	return entries
+}
+
+// replaceHostsBlock replaces the block between the marker lines of the hosts
+// file content with the entries, appending it if it's missing, or removing
+// it if there is no entry.
+func replaceHostsBlock(content string, marker string, entries []HostEntry) string {
+	begin, end := "# BEGIN "+marker, "# END "+marker
+	var lines []string
+	inBlock := false
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		switch {
+		case strings.TrimSpace(line) == begin:
+			inBlock = true
+		case strings.TrimSpace(line) == end:
+			inBlock = false
+		case !inBlock:
+			lines = append(lines, line)
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(entries) > 0 {
+		lines = append(lines, begin)
+		for _, entry := range entries {
+			lines = append(lines, entry.String())
+		}
+		lines = append(lines, end)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// SyncHosts writes the entries of all the running machines into the hosts
+// file of each of them, so that they resolve each other whatever their
+// networks.
+func (c *Cluster) SyncHosts(ctx context.Context) error {
+	machines, err := c.runningMachines()
+	if err != nil {
+		return err
+	}
+	return forMachinesInParallel(machines, func(m *Machine) error {
+		content, err := m.readFile(ctx, HOSTS_FILE_PATH)
+		if err != nil {
+			return err
+		}
+		updated := replaceHostsBlock(string(content), c.hostsMarker(), hostEntries(m, machines))
+		if updated == string(content) {
+			return nil
+		}
+		m.logger().Debugf("Updating %s of machine %s", HOSTS_FILE_PATH, m.machineName)
+		return m.runInput(ctx, []byte(updated), "/bin/sh", "-c", HOSTS_WRITE_SCRIPT)
+	})
+}
+
+// syncHosts is SyncHosts for the commands changing the machines, which
+// shouldn't fail because of it.
+func (c *Cluster) syncHosts(ctx context.Context) {
+	if err := c.SyncHosts(ctx); err != nil {
+		c.logger().Warnf("Can't update the hosts files of the machines: %v", err)
+	}
+}
+
+// HostEntries returns the hosts entries of the running machines, as seen
+// from the host.
+func (c *Cluster) HostEntries() ([]HostEntry, error) {
+	machines, err := c.runningMachines()
+	if err != nil {
+		return nil, err
+	}
+	return hostEntries(nil, machines), nil
+}
+
+// SyncHostsFile writes the entries of the running machines into the block
+// of the cluster in the hosts file of the host, like /etc/hosts.
+func (c *Cluster) SyncHostsFile(path string) error {
+	entries, err := c.HostEntries()
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "hosts: read")
+	}
+	updated := replaceHostsBlock(string(content), c.hostsMarker(), entries)
+	if updated == string(content) {
+		c.logger().Infof("%s is up to date", path)
+		return nil
+	}
+	if c.dryRun {
+		c.logger().Infof("Dry run: updating %s with %d machine(s)", path, len(entries))
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(updated), info.Mode().Perm()); err != nil {
+		if os.IsPermission(err) {
+			return fmt.Errorf("can't write %s, try again with sudo, or as Administrator on Windows: %w", path, err)
+		}
+		return errors.Wrap(err, "hosts: write")
+	}
+	c.logger().Infof("Updated %s with %d machine(s)", path, len(entries))
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceHostsBlock(t *testing.T) {
+	entries := []HostEntry{{IP: "172.18.0.2", Names: []string{"node0.c.vind", "node0"}}}
+	content := "127.0.0.1\tlocalhost\n"
+
+	added := replaceHostsBlock(content, "vind c", entries)
+	assert.Equal(t, "127.0.0.1\tlocalhost\n# BEGIN vind c\n172.18.0.2\tnode0.c.vind node0\n# END vind c\n", added)
+
+	// replaced in place, other blocks kept
+	other := added + "# BEGIN vind other\n172.19.0.2\tnode0.other.vind node0\n# END vind other\n"
+	entries[0].IP = "172.18.0.3"
+	replaced := replaceHostsBlock(other, "vind c", entries)
+	assert.Equal(t, "127.0.0.1\tlocalhost\n# BEGIN vind other\n172.19.0.2\tnode0.other.vind node0\n# END vind other\n# BEGIN vind c\n172.18.0.3\tnode0.c.vind node0\n# END vind c\n", replaced)
+
+	// idempotent
+	assert.Equal(t, replaced, replaceHostsBlock(replaced, "vind c", entries))
+
+	// removed without entries
+	assert.Equal(t, content, replaceHostsBlock(added, "vind c", nil))
+	assert.Equal(t, "", replaceHostsBlock("", "vind c", nil))
+}
+
+func TestHostEntries(t *testing.T) {
+	cluster := &config.Cluster{Name: "C", Domain: "test"}
+	machineSet := &config.MachineSet{Name: "set"}
+	spec := &config.Machine{Name: "node%d"}
+	m0 := newMachine(cluster, machineSet, spec, 0)
+	m0.runtimeNetworks = []*RuntimeNetwork{{Name: "front", IP: "10.0.0.2"}, {Name: "back", IP: "10.1.0.2"}}
+	m1 := newMachine(cluster, machineSet, spec, 1)
+	m1.runtimeNetworks = []*RuntimeNetwork{{Name: "back", IP: "10.1.0.3"}}
+
+	// reachable through a shared network
+	entries := hostEntries(m1, []*Machine{m0, m1})
+	assert.Equal(t, []HostEntry{
+		{IP: "10.1.0.2", Names: []string{"set-node0.c.test", "set-node0"}},
+		{IP: "10.1.0.3", Names: []string{"set-node1.c.test", "set-node1"}},
+	}, entries)
+
+	// from the host, the first network
+	entries = hostEntries(nil, []*Machine{m0})
+	assert.Equal(t, "10.0.0.2", entries[0].IP)
+
+	// aliased in the user defined networks
+	m0.spec.Networks = []string{"front"}
+	assert.Contains(t, m0.generateContainerRunArgs("C"), "set-node0.c.test")
+}
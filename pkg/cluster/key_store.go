@@ -19,24 +19,42 @@ package cluster
 import (
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/brightzheng100/vind/pkg/utils"
 	"github.com/pkg/errors"
+	gossh "golang.org/x/crypto/ssh"
 )
 
 // KeyStore is a store for public keys.
 type KeyStore struct {
 	basePath string
+	// dryRun leaves the stored keys alone, logging the changes instead.
+	dryRun bool
 }
 
-// NewKeyStore creates a new KeyStore
+// NewKeyStore creates a new KeyStore, which only logs its changes in dry
+// runs, see exec.DryRun.
 func NewKeyStore(basePath string) *KeyStore {
 	return &KeyStore{
 		basePath: basePath,
+		dryRun:   exec.DryRun(),
 	}
 }
 
+// DefaultKeyStore returns the key store kept in the vind home directory, which
+// is shared by all clusters.
+func DefaultKeyStore() *KeyStore {
+	return NewKeyStore(filepath.Join(Home(), "keys"))
+}
+
 // Init initializes the key store, creating the store directory if needed.
 func (s *KeyStore) Init() error {
+	if s.dryRun {
+		return nil
+	}
 	return os.MkdirAll(s.basePath, 0760)
 }
 
@@ -57,10 +75,20 @@ func (s *KeyStore) keyExists(name string) bool {
 
 // Store adds the key to the store.
 func (s *KeyStore) Store(name, key string) error {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return errors.Errorf("key store: store: invalid key name '%s'", name)
+	}
 	if s.keyExists(name) {
 		return errors.Errorf("key store: store: key '%s' already exists", name)
 	}
+	if _, _, _, _, err := gossh.ParseAuthorizedKey([]byte(key)); err != nil {
+		return errors.Errorf("key store: store: key '%s' is not a valid public key", name)
+	}
 
+	if s.dryRun {
+		utils.Logger.Infof("Dry run: writing %s", s.keyPath(name))
+		return nil
+	}
 	if err := os.WriteFile(s.keyPath(name), []byte(key), 0644); err != nil {
 		return errors.Wrap(err, "key store: write")
 	}
@@ -81,8 +109,44 @@ func (s *KeyStore) Remove(name string) error {
 	if !s.keyExists(name) {
 		return errors.Errorf("key store: remove: unknown key '%s'", name)
 	}
+	if s.dryRun {
+		utils.Logger.Infof("Dry run: removing %s", s.keyPath(name))
+		return nil
+	}
 	if err := os.Remove(s.keyPath(name)); err != nil {
 		return errors.Wrap(err, "key store: remove")
 	}
 	return nil
 }
+
+// List returns the names of the keys in the store.
+func (s *KeyStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.basePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "key store: list")
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Describe returns the description of a key in the store.
+func (s *KeyStore) Describe(name string) (*AuthorizedKey, error) {
+	data, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	keys := parseAuthorizedKeys(data)
+	if len(keys) < 1 {
+		return nil, errors.Errorf("key store: describe: key '%s' is not a valid public key", name)
+	}
+	return &keys[0], nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyStore(t *testing.T) {
+	store := NewKeyStore(t.TempDir() + "/keys")
+
+	names, err := store.List()
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+
+	assert.NoError(t, store.Init())
+	_, alice, _ := generateKey(config.KeyTypeED25519, "alice@example.com")
+	_, bob, _ := generateKey(config.KeyTypeRSA, "bob@example.com")
+	assert.NoError(t, store.Store("bob", string(bob)))
+	assert.NoError(t, store.Store("alice", string(alice)))
+
+	assert.Error(t, store.Store("alice", string(alice)), "duplicated key")
+	assert.Error(t, store.Store("carol", "not a key"), "invalid key")
+	assert.Error(t, store.Store("../carol", string(alice)), "invalid name")
+
+	names, err = store.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, names)
+
+	key, err := store.Describe("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "ssh-ed25519", key.Type)
+	assert.Equal(t, "alice@example.com", key.Comment)
+
+	assert.NoError(t, store.Remove("alice"))
+	assert.Error(t, store.Remove("alice"))
+	_, err = store.Get("alice")
+	assert.Error(t, err)
+}
+
+func TestPublicKey(t *testing.T) {
+	store := NewKeyStore(t.TempDir())
+	_, alice, _ := generateKey(config.KeyTypeED25519, "alice@example.com")
+	_, bob, _ := generateKey(config.KeyTypeED25519, "bob@example.com")
+	assert.NoError(t, store.Store("alice", string(alice)))
+	assert.NoError(t, store.Store("bob", string(bob)))
+
+	c := &Cluster{keyStore: store}
+	spec := &config.Machine{
+		User:           "ubuntu",
+		PublicKey:      "alice",
+		PublicKeys:     []string{"bob"},
+		UserPublicKeys: map[string][]string{"root": {"bob"}},
+	}
+
+	keys, err := c.publicKey(spec, "ubuntu")
+	assert.NoError(t, err)
+	assert.Equal(t, string(alice)+string(bob), string(keys))
+
+	keys, err = c.publicKey(spec, "root")
+	assert.NoError(t, err)
+	assert.Equal(t, string(bob), string(keys))
+
+	keys, err = c.publicKey(spec, "nobody")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	spec.PublicKeys = []string{"carol"}
+	_, err = c.publicKey(spec, "ubuntu")
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/pkg/errors"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HOST_KEYS_SCRIPT makes sure the machine has host keys and prints them.
+const HOST_KEYS_SCRIPT = `
+if command -v ssh-keygen >/dev/null 2>&1; then
+	ssh-keygen -A >/dev/null
+fi
+cat /etc/ssh/ssh_host_*_key.pub
+`
+
+// KnownHostsPath returns the path of the known_hosts file of the cluster,
+// where the host keys of the machines are recorded.
+func (c *Cluster) KnownHostsPath() string {
+	return filepath.Join(c.Dir(), "known_hosts")
+}
+
+// hostKeys collects the SSH host public keys of a running machine.
+func (m *Machine) hostKeys(ctx context.Context) ([]gossh.PublicKey, error) {
+	lines, err := m.output(ctx, "/bin/sh", "-c", HOST_KEYS_SCRIPT)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't collect host keys of %s", m.machineName)
+	}
+	if exec.DryRun() {
+		// the machines aren't really running
+		return nil, nil
+	}
+	var keys []gossh.PublicKey
+	for _, line := range lines {
+		key, _, _, _, err := gossh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) < 1 {
+		return nil, errors.Errorf("no host key found on %s", m.machineName)
+	}
+	return keys, nil
+}
+
+// refreshKnownHosts records the host keys of a running machine in the cluster
+// known_hosts file, replacing the machine's previous entries. The keys are
+// recorded under the container name, the alias used when tunnelling SSH over
+// docker exec, and under the published SSH address if there is one.
+func (c *Cluster) refreshKnownHosts(ctx context.Context, m *Machine) error {
+	if c.dryRun {
+		return nil
+	}
+	addresses := []string{m.containerName}
+	if bindings, err := m.HostPorts(22, "tcp"); err == nil {
+		host, port := sshEndpoint(bindings)
+		addresses = append([]string{knownhosts.Normalize(net.JoinHostPort(host, strconv.Itoa(port)))}, addresses...)
+	} else {
+		m.logger().Debugf("Machine %s has no SSH port, recording its host keys by name only: %v", m.machineName, err)
+	}
+	keys, err := m.hostKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.logger().Debugf("Recording host keys of machine %s as %v", m.machineName, addresses)
+	return c.updateKnownHostsFile(func(content []byte) []byte {
+		return updateKnownHosts(content, m.containerName, addresses, keys)
+	})
+}
+
+// forgetKnownHosts removes the entries of a machine from the cluster known_hosts file.
+func (c *Cluster) forgetKnownHosts(m *Machine) error {
+	if !fileExists(c.KnownHostsPath()) {
+		return nil
+	}
+	return c.updateKnownHostsFile(func(content []byte) []byte {
+		return updateKnownHosts(content, m.containerName, nil, nil)
+	})
+}
+
+func (c *Cluster) updateKnownHostsFile(update func([]byte) []byte) error {
+	path := c.KnownHostsPath()
+	if c.dryRun {
+		c.logger().Infof("Dry run: updating %s", path)
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "known hosts: read")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrap(err, "known hosts: create directory")
+	}
+	if err := os.WriteFile(path, update(content), 0600); err != nil {
+		return errors.Wrap(err, "known hosts: write")
+	}
+	return nil
+}
+
+// updateKnownHosts replaces the entries of a machine, identified by the
+// trailing comment of its lines, and any entry of its addresses with its keys.
+func updateKnownHosts(content []byte, machine string, addresses []string, keys []gossh.PublicKey) []byte {
+	var updated bytes.Buffer
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(fields[0], "@") && !strings.HasPrefix(fields[0], "#") &&
+			(hasAnyAddress(fields[0], addresses) || (len(fields) > 3 && fields[len(fields)-1] == machine)) {
+			continue
+		}
+		updated.WriteString(line)
+		updated.WriteByte('\n')
+	}
+	for _, key := range keys {
+		updated.WriteString(knownhosts.Line(addresses, key))
+		updated.WriteString(" " + machine + "\n")
+	}
+	return updated.Bytes()
+}
+
+// hasAnyAddress returns whether the comma separated hosts field of a
+// known_hosts line contains any of the addresses.
+func hasAnyAddress(hosts string, addresses []string) bool {
+	for _, host := range strings.Split(hosts, ",") {
+		if slices.Contains(addresses, host) {
+			return true
+		}
+	}
+	return false
+}
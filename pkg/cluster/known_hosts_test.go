@@ -0,0 +1,66 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/stretchr/testify/assert"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func publicKeyOf(t *testing.T, data []byte) gossh.PublicKey {
+	key, _, _, _, err := gossh.ParseAuthorizedKey(data)
+	assert.NoError(t, err)
+	return key
+}
+
+func TestUpdateKnownHosts(t *testing.T) {
+	_, pub1, _ := generateKey(config.KeyTypeED25519, "")
+	_, pub2, _ := generateKey(config.KeyTypeECDSA, "")
+	key1, key2 := publicKeyOf(t, pub1), publicKeyOf(t, pub2)
+
+	content := updateKnownHosts(nil, "cluster-test-node0", []string{"[localhost]:32768"}, []gossh.PublicKey{key1, key2})
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "[localhost]:32768 ssh-ed25519 "))
+	assert.True(t, strings.HasSuffix(lines[0], " cluster-test-node0"))
+
+	content = updateKnownHosts(content, "cluster-test-node1", []string{"[localhost]:32769"}, []gossh.PublicKey{key1})
+	assert.Equal(t, 3, strings.Count(string(content), "\n"))
+
+	// the machine got a new port after a restart
+	content = updateKnownHosts(content, "cluster-test-node0", []string{"[localhost]:32770", "cluster-test-node0"}, []gossh.PublicKey{key2})
+	assert.NotContains(t, string(content), "[localhost]:32768")
+	assert.Contains(t, string(content), "[localhost]:32770,cluster-test-node0 ecdsa-sha2-nistp256 ")
+	assert.Equal(t, 2, strings.Count(string(content), "\n"))
+
+	// another machine got the port of a deleted one
+	content = updateKnownHosts(content, "cluster-test-node2", []string{"[localhost]:32769"}, []gossh.PublicKey{key2})
+	assert.NotContains(t, string(content), "cluster-test-node1")
+
+	// the machine is only reachable by name
+	content = updateKnownHosts(content, "cluster-test-node3", []string{"cluster-test-node3"}, []gossh.PublicKey{key1})
+	assert.Contains(t, string(content), "cluster-test-node3 ssh-ed25519 ")
+	assert.Equal(t, 3, strings.Count(string(content), "\n"))
+	content = updateKnownHosts(content, "cluster-test-node3", nil, nil)
+
+	content = updateKnownHosts(content, "cluster-test-node2", nil, nil)
+	assert.Equal(t, 1, strings.Count(string(content), "\n"))
+	assert.Contains(t, string(content), "cluster-test-node0")
+}
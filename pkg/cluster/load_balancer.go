@@ -0,0 +1,222 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/brightzheng100/vind/pkg/docker"
+	"github.com/brightzheng100/vind/pkg/proxy"
+	"github.com/pkg/errors"
+)
+
+const (
+	// backendsRefresh is how often the backends of the load balancers are
+	// looked up, so that they follow the replicas of the MachineSets.
+	backendsRefresh = 5 * time.Second
+	// defaultLoadBalancerAddress is the host address load balancers listen
+	// to by default.
+	defaultLoadBalancerAddress = "127.0.0.1"
+	defaultHealthCheckInterval = 5 * time.Second
+	defaultHealthCheckTimeout  = 2 * time.Second
+)
+
+// LoadBalance runs the named load balancers, or all of them if no name is
+// given, until the context is done.
+func (c *Cluster) LoadBalance(ctx context.Context, names []string, via string) error {
+	var lbs []config.LoadBalancer
+	for _, lb := range c.config.LoadBalancers {
+		if len(names) == 0 || slices.Contains(names, lb.Name) {
+			lbs = append(lbs, lb)
+		}
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(lbs, func(lb config.LoadBalancer) bool { return lb.Name == name }) {
+			return fmt.Errorf("load balancer not found: %s", name)
+		}
+	}
+	if len(lbs) == 0 {
+		return errors.New("no load balancer is configured")
+	}
+
+	via = forwardVia(via)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(lbs))
+	for _, lb := range lbs {
+		address := lb.Address
+		if address == "" {
+			address = defaultLoadBalancerAddress
+		}
+		listener, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(int(lb.Port))))
+		if err != nil {
+			return errors.Wrapf(err, "load balancer %s", lb.Name)
+		}
+		defer listener.Close()
+		c.logger().Infof("Load balancer %s: balancing %s over machineSet %s, port %d, via %s", lb.Name, listener.Addr(), lb.MachineSet, lb.TargetPort, via)
+
+		balancer := proxy.NewBalancer(lb.Algorithm, lb.HealthCheck != nil)
+		go c.refreshBackends(ctx, lb, via, balancer)
+		if hc := lb.HealthCheck; hc != nil {
+			interval, timeout := healthCheckDurations(hc)
+			go balancer.HealthCheck(ctx, interval, timeout)
+		}
+		go func() {
+			errs <- errors.Wrapf(balancer.Serve(ctx, listener), "load balancer %s", lb.Name)
+		}()
+	}
+
+	// the first failure stops all the load balancers
+	err := <-errs
+	cancel()
+	for range lbs[1:] {
+		if e := <-errs; err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// refreshBackends looks up the running machines of the MachineSet of the load
+// balancer periodically, until the context is done.
+func (c *Cluster) refreshBackends(ctx context.Context, lb config.LoadBalancer, via string, balancer *proxy.Balancer) {
+	ticker := time.NewTicker(backendsRefresh)
+	defer ticker.Stop()
+	for {
+		backends, err := c.loadBalancerBackends(ctx, lb, via)
+		if err != nil {
+			c.logger().Warnf("Load balancer %s: looking up backends: %v", lb.Name, err)
+		} else {
+			added, removed := balancer.SetBackends(backends)
+			for _, name := range added {
+				c.logger().Infof("Load balancer %s: added backend %s", lb.Name, name)
+			}
+			for _, name := range removed {
+				c.logger().Infof("Load balancer %s: removed backend %s", lb.Name, name)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// loadBalancerBackends returns the running machines of the MachineSet of the
+// load balancer as backends.
+func (c *Cluster) loadBalancerBackends(ctx context.Context, lb config.LoadBalancer, via string) ([]proxy.Backend, error) {
+	containers, err := c.runtime.List(ctx, "creator=vind", "cluster="+c.Name())
+	if err != nil {
+		return nil, err
+	}
+	checkPort := int(lb.TargetPort)
+	if lb.HealthCheck != nil && lb.HealthCheck.Port != 0 {
+		checkPort = int(lb.HealthCheck.Port)
+	}
+
+	machineSet := &config.MachineSet{Name: lb.MachineSet}
+	for i := range c.config.MachineSets {
+		if c.config.MachineSets[i].Name == lb.MachineSet {
+			machineSet = &c.config.MachineSets[i]
+		}
+	}
+
+	var backends []proxy.Backend
+	for _, container := range containers {
+		if !inMachineSet(container, c.Name(), machineSet) {
+			continue
+		}
+		m := &Machine{
+			cluster:       c.Name(),
+			containerName: container.Name,
+			machineName:   strings.TrimPrefix(container.Name, c.Name()+"-"),
+			machineSet:    lb.MachineSet,
+			runtime:       c.runtime,
+		}
+		backend := proxy.Backend{Name: m.machineName}
+		switch via {
+		case ForwardViaExec:
+			dial := execDialer(ctx, m)
+			backend.Dial = func() (io.ReadWriteCloser, error) { return dial("tcp", int(lb.TargetPort)) }
+			backend.Check = func(ctx context.Context) error {
+				timeout := "2"
+				if deadline, ok := ctx.Deadline(); ok {
+					timeout = strconv.Itoa(max(1, int(time.Until(deadline).Seconds())))
+				}
+				return m.runtime.Cmder(ctx, m.containerName).Command("nc", "-z", "-w", timeout, "localhost", strconv.Itoa(checkPort)).Run()
+			}
+		default:
+			ip, err := m.firstIP()
+			if err != nil {
+				m.logger().Warnf("Load balancer %s: skipping backend %s: %v", lb.Name, m.machineName, err)
+				continue
+			}
+			dial := proxy.NetDialer(ip)
+			backend.Dial = func() (io.ReadWriteCloser, error) { return dial("tcp", int(lb.TargetPort)) }
+			backend.Check = func(ctx context.Context) error {
+				var d net.Dialer
+				conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(checkPort)))
+				if err != nil {
+					return err
+				}
+				return conn.Close()
+			}
+		}
+		backends = append(backends, backend)
+	}
+	return backends, nil
+}
+
+// inMachineSet returns whether the container is a machine of the MachineSet.
+// Containers created before the machineSet label are recognized by their
+// whole name, so that MachineSet "web" doesn't match "web-canary" machines.
+func inMachineSet(container docker.ContainerSummary, cluster string, machineSet *config.MachineSet) bool {
+	if set, ok := container.Labels["machineSet"]; ok {
+		return set == machineSet.Name
+	}
+	return containerNameRegexp(cluster, machineSet).MatchString(container.Name)
+}
+
+// containerNameRegexp matches the container names of the machines of the
+// MachineSet, whatever their index.
+func containerNameRegexp(cluster string, machineSet *config.MachineSet) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(cluster + "-" + machineSet.Name + "-" + machineSet.Spec.Name)
+	// the index is formatted by a verb like %d or %02d
+	pattern = regexp.MustCompile(`%[0-9]*d`).ReplaceAllString(pattern, "[0-9]+")
+	pattern = strings.ReplaceAll(pattern, "%%", "%")
+	return regexp.MustCompile("^" + pattern + "$")
+}
+
+func healthCheckDurations(hc *config.HealthCheck) (time.Duration, time.Duration) {
+	interval, timeout := defaultHealthCheckInterval, defaultHealthCheckTimeout
+	if d, err := time.ParseDuration(hc.Interval); err == nil {
+		interval = d
+	}
+	if d, err := time.ParseDuration(hc.Timeout); err == nil {
+		timeout = d
+	}
+	return interval, timeout
+}
@@ -0,0 +1,58 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/brightzheng100/vind/pkg/docker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMachineSet(t *testing.T) {
+	web := &config.MachineSet{Name: "web", Spec: config.Machine{Name: "node%d"}}
+	canary := &config.MachineSet{Name: "web-canary", Spec: config.Machine{Name: "node%d"}}
+	db := &config.MachineSet{Name: "db", Spec: config.Machine{Name: "db%02d"}}
+
+	labeled := docker.ContainerSummary{Name: "cluster-web-canary-node0", Labels: map[string]string{"machineSet": "web-canary"}}
+	assert.True(t, inMachineSet(labeled, "cluster", canary))
+	assert.False(t, inMachineSet(labeled, "cluster", web))
+
+	// created before the machineSet label
+	unlabeled := docker.ContainerSummary{Name: "cluster-web-node1", Labels: map[string]string{"cluster": "cluster"}}
+	assert.True(t, inMachineSet(unlabeled, "cluster", web))
+	assert.False(t, inMachineSet(unlabeled, "cluster", db))
+	unlabeled = docker.ContainerSummary{Name: "cluster-web-canary-node0", Labels: map[string]string{"cluster": "cluster"}}
+	assert.True(t, inMachineSet(unlabeled, "cluster", canary))
+	assert.False(t, inMachineSet(unlabeled, "cluster", web))
+	unlabeled = docker.ContainerSummary{Name: "cluster-db-db01", Labels: map[string]string{"cluster": "cluster"}}
+	assert.True(t, inMachineSet(unlabeled, "cluster", db))
+
+	m := newMachine(&config.Cluster{Name: "cluster"}, &config.MachineSet{Name: "web"}, &config.Machine{Name: "node%d"}, 0)
+	args := m.generateContainerRunArgs("cluster")
+	assert.Contains(t, args, "machineSet=web")
+}
+
+func TestHealthCheckDurations(t *testing.T) {
+	interval, timeout := healthCheckDurations(&config.HealthCheck{})
+	assert.Equal(t, defaultHealthCheckInterval, interval)
+	assert.Equal(t, defaultHealthCheckTimeout, timeout)
+
+	interval, timeout = healthCheckDurations(&config.HealthCheck{Interval: "1m", Timeout: "500ms"})
+	assert.Equal(t, "1m0s", interval.String())
+	assert.Equal(t, "500ms", timeout.String())
+}
@@ -0,0 +1,169 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lockPollInterval is how often a held lock is tried again while waiting.
+const lockPollInterval = 100 * time.Millisecond
+
+// LockHolder is the process holding the lock of a cluster.
+type LockHolder struct {
+	PID     int       `json:"pid"`
+	Command string    `json:"command"`
+	Since   time.Time `json:"since"`
+}
+
+// LockedError is returned when the lock of a cluster is held by another
+// process. It wraps ErrLocked.
+type LockedError struct {
+	Cluster string
+	// Holder is the process holding the lock, nil if unknown.
+	Holder *LockHolder
+	// Waited is how long the lock was waited for.
+	Waited time.Duration
+}
+
+func (e *LockedError) Error() string {
+	msg := f("cluster %s is locked", e.Cluster)
+	if e.Holder != nil {
+		msg += f(" by pid %d (%s) since %s", e.Holder.PID, e.Holder.Command, e.Holder.Since.Format(time.RFC3339))
+	} else {
+		msg += " by another process"
+	}
+	if e.Waited > 0 {
+		msg += f(", waited %s", e.Waited)
+	}
+	return msg
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrLocked
+}
+
+// LockPath returns the path of the lock file of the cluster.
+func (c *Cluster) LockPath() string {
+	return filepath.Join(c.Dir(), "lock")
+}
+
+// Lock takes the lock of the cluster, so that the processes changing it don't
+// race, and returns the function releasing it. A lock held by another process
+// is waited for up to wait, 0 failing right away with a *LockedError naming
+// the holder. The lock is released by the system if the process dies. A dry
+// run neither creates the lock file nor records itself as the holder.
+func (c *Cluster) Lock(ctx context.Context, wait time.Duration) (unlock func(), err error) {
+	if c.dryRun && !fileExists(c.LockPath()) {
+		// nobody has ever held the lock
+		c.logger().Infof("Dry run: locking %s", c.LockPath())
+		return func() {}, nil
+	}
+	if err := os.MkdirAll(c.Dir(), 0700); err != nil {
+		return nil, errors.Wrap(err, "lock: create cluster directory")
+	}
+	start := time.Now()
+	file, err := lockFile(ctx, c.LockPath(), wait)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		waited := time.Since(start).Round(time.Millisecond)
+		return nil, &LockedError{Cluster: c.Name(), Holder: readLockHolder(c.LockPath()), Waited: waited}
+	}
+
+	if c.dryRun {
+		return func() {
+			unlockFile(file)
+			file.Close()
+		}, nil
+	}
+	writeLockHolder(file, &LockHolder{
+		PID:     os.Getpid(),
+		Command: strings.Join(os.Args, " "),
+		Since:   time.Now(),
+	})
+	return func() {
+		file.Truncate(0)
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
+
+// lockFile takes an exclusive lock on the file at path, created if needed,
+// waiting for it up to wait. It returns the locked file, or nil if another
+// process still holds the lock.
+func lockFile(ctx context.Context, path string, wait time.Duration) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "lock: open")
+	}
+	start := time.Now()
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, errors.Wrapf(err, "lock: %s", path)
+		}
+		if locked {
+			return file, nil
+		}
+		waited := time.Since(start)
+		if waited >= wait {
+			file.Close()
+			return nil, nil
+		}
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		case <-time.After(min(lockPollInterval, wait-waited)):
+		}
+	}
+}
+
+// readLockHolder reads the holder recorded in the lock file, if any.
+func readLockHolder(path string) *LockHolder {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var holder LockHolder
+	if err := json.Unmarshal(data, &holder); err != nil || holder.PID == 0 {
+		return nil
+	}
+	return &holder
+}
+
+// writeLockHolder records the holder into the lock file, for the processes
+// waiting for it. It's informative only, so errors are ignored.
+func writeLockHolder(file *os.File, holder *LockHolder) {
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return
+	}
+	if err := file.Truncate(0); err != nil {
+		return
+	}
+	file.WriteAt(append(data, '\n'), 0)
+}
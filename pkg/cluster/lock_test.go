@@ -0,0 +1,80 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newFakeCluster(t)
+
+	unlock, err := c.Lock(ctx, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = c.Lock(ctx, 0)
+	assert.True(t, errors.Is(err, ErrLocked), "%v", err)
+	var locked *LockedError
+	if assert.True(t, errors.As(err, &locked)) && assert.NotNil(t, locked.Holder) {
+		assert.Equal(t, os.Getpid(), locked.Holder.PID)
+		assert.Equal(t, strings.Join(os.Args, " "), locked.Holder.Command)
+		assert.Contains(t, err.Error(), f("cluster fake is locked by pid %d (", os.Getpid()))
+	}
+
+	// waiting in vain
+	start := time.Now()
+	_, err = c.Lock(ctx, 200*time.Millisecond)
+	assert.True(t, errors.Is(err, ErrLocked), "%v", err)
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+	assert.Contains(t, err.Error(), "waited")
+
+	// waiting until the holder is done
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		unlock()
+	}()
+	unlock, err = c.Lock(ctx, 5*time.Second)
+	if assert.NoError(t, err) {
+		unlock()
+	}
+	unlock, err = c.Lock(ctx, 0)
+	if assert.NoError(t, err) {
+		unlock()
+	}
+}
+
+func TestLockCancelled(t *testing.T) {
+	c, _ := newFakeCluster(t)
+	unlock, err := c.Lock(context.Background(), 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = c.Lock(ctx, time.Minute)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+}
@@ -0,0 +1,49 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// logger returns the logger of the cluster, its entries carrying the name of
+// the cluster.
+func (c *Cluster) logger() *logrus.Entry {
+	return utils.Logger.WithField(utils.FieldCluster, c.Name())
+}
+
+// logger returns the logger of the machine, its entries carrying the names of
+// the cluster, the machine set, the machine and its container.
+func (m *Machine) logger() *logrus.Entry {
+	return utils.Logger.WithFields(logrus.Fields{
+		utils.FieldCluster:    m.cluster,
+		utils.FieldMachineSet: m.machineSet,
+		utils.FieldMachine:    m.machineName,
+		utils.FieldContainer:  m.containerName,
+	})
+}
+
+// withOperation adds the operation, and how long it took since start, to the
+// fields of the entries.
+func withOperation(entry *logrus.Entry, operation string, start time.Time) *logrus.Entry {
+	return entry.WithFields(logrus.Fields{
+		utils.FieldOperation: operation,
+		utils.FieldDuration:  time.Since(start).Round(time.Millisecond).Seconds(),
+	})
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructuredLogs(t *testing.T) {
+	var logs bytes.Buffer
+	utils.Logger.SetOutput(&logs)
+	assert.NoError(t, utils.ConfigureLogger("info", "json", false))
+	t.Cleanup(func() {
+		utils.Logger.SetOutput(os.Stderr)
+		utils.ConfigureLogger("info", "text", false)
+	})
+
+	c, _ := newFakeCluster(t)
+	assert.NoError(t, c.Create(context.Background(), CreateOptions{}))
+
+	var entries []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+		var entry map[string]interface{}
+		if assert.NoError(t, json.Unmarshal(line, &entry), "%s", line) {
+			entries = append(entries, entry)
+		}
+	}
+	// the entry of the operation of the machine, or of the cluster for nil
+	find := func(operation string, machine interface{}) map[string]interface{} {
+		for _, entry := range entries {
+			if entry[utils.FieldOperation] == operation && entry[utils.FieldMachine] == machine {
+				return entry
+			}
+		}
+		return nil
+	}
+
+	node1 := find("create", "nodes-node1")
+	if assert.NotNil(t, node1, "%s", logs.String()) {
+		assert.Equal(t, "fake", node1[utils.FieldCluster])
+		assert.Equal(t, "nodes", node1[utils.FieldMachineSet])
+		assert.Equal(t, "fake-nodes-node1", node1[utils.FieldContainer])
+		assert.IsType(t, float64(0), node1[utils.FieldDuration])
+		assert.Equal(t, "Created machine nodes-node1", node1["msg"])
+	}
+	cluster := find("create", nil)
+	if assert.NotNil(t, cluster) {
+		assert.Equal(t, "fake", cluster[utils.FieldCluster])
+		assert.Equal(t, "Created cluster fake", cluster["msg"])
+	}
+}
+
+func TestConfigureLogger(t *testing.T) {
+	t.Cleanup(func() { utils.ConfigureLogger("info", "text", false) })
+
+	assert.NoError(t, utils.ConfigureLogger("debug", "text", false))
+	assert.Equal(t, logrus.DebugLevel, utils.Logger.GetLevel())
+	assert.NoError(t, utils.ConfigureLogger("debug", "json", true))
+	assert.Equal(t, logrus.ErrorLevel, utils.Logger.GetLevel())
+	assert.IsType(t, &logrus.JSONFormatter{}, utils.Logger.Formatter)
+	assert.Error(t, utils.ConfigureLogger("loud", "text", false))
+	assert.Error(t, utils.ConfigureLogger("info", "xml", false))
+}
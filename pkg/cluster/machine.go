@@ -17,16 +17,18 @@ limitations under the License.
 package cluster
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/brightzheng100/vind/pkg/config"
-	"github.com/brightzheng100/vind/pkg/docker"
-	"github.com/brightzheng100/vind/pkg/exec"
-	"github.com/brightzheng100/vind/pkg/utils"
-	"github.com/docker/docker/api/types/network"
+	"github.com/brightzheng100/vind/pkg/runtime"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
 	"github.com/pkg/errors"
 )
 
@@ -47,16 +49,74 @@ else
 	chown -R $u:$u /home/$u/
 fi
 `
+const USER_SCRIPT = `
+set -e
+u=%s
+uid=%s
+shell=%s
+default_shell=%s
+groups=%s
+sudo=%s
+for g in ${groups//,/ }; do
+	getent group $g >/dev/null || groupadd $g
+done
+if ! id -u $u >/dev/null 2>&1; then
+	useradd -m ${uid:+-u $uid} -s ${shell:-$default_shell} ${groups:+-G $groups} $u
+elif [[ -n "$shell$groups" ]]; then
+	usermod ${shell:+-s $shell} ${groups:+-a -G $groups} $u
+fi
+if [[ "$sudo" == "nopasswd" ]]; then
+	mkdir -p /etc/sudoers.d
+	echo "$u ALL=(ALL:ALL) NOPASSWD: ALL" > /etc/sudoers.d/$u
+	chmod 440 /etc/sudoers.d/$u
+fi
+`
+
+// LOGIN_USER_SCRIPT creates the machine user when it's not listed in the users
+// of the machine spec and doesn't exist in the image, with password-less sudo
+// like the former non-root images had.
+const LOGIN_USER_SCRIPT = `
+set -e
+u=%s
+shell=%s
+if ! id -u $u >/dev/null 2>&1; then
+	useradd -m -s $shell $u
+	mkdir -p /etc/sudoers.d
+	echo "$u ALL=(ALL:ALL) NOPASSWD: ALL" > /etc/sudoers.d/$u
+	chmod 440 /etc/sudoers.d/$u
+fi
+`
+
+// EXEC_SHELL_SCRIPT starts the login shell of the current user in the
+// directory given as first argument, or home, running the remaining
+// arguments as a command if any.
+const EXEC_SHELL_SCRIPT = `
+dir=$1; shift
+shell=$(awk -F: -v u="$(id -un)" '$1 == u { print $7 }' /etc/passwd)
+[ -x "$shell" ] || shell=/bin/sh
+cd "${dir:-$HOME}" 2>/dev/null || cd "$HOME" 2>/dev/null
+if [ $# -gt 0 ]; then
+	exec "$shell" -l -c "$*"
+fi
+exec "$shell" -l
+`
 
 // defaultUser is the default container user.
 const defaultUser = "root"
 
+// defaultShell is the default login shell of the users created by vind.
+const defaultShell = "/bin/bash"
+
 // Machine is a running machine instance.
 type Machine struct {
 	spec *config.Machine
 
+	// machineSet is the name of the machine set
+	machineSet string
 	// index in the machine set
 	index int
+	// cluster is the name of the cluster of the machine.
+	cluster string
 
 	// containerName is the container name in underlying platform.
 	// Naming pattern: {cluster name}-{machineSet name}-{machineName with index}
@@ -64,34 +124,76 @@ type Machine struct {
 	// machineName is the machine's name which is also the host's name.
 	// Naming pattern: {machineSet name}-{node name with index}
 	machineName string
+	// fqdn is the fully qualified domain name of the machine.
+	// Naming pattern: {machineName}.{cluster name}.{domain}
+	fqdn string
 
 	// runtimeNetwork are networks in Docker runtime
 	runtimeNetworks []*RuntimeNetwork
 
-	ports map[int]int
-	// maps containerPort -> hostPort.
+	// portMap caches the published ports of the container,
+	// keyed by "containerPort/protocol".
+	portMap nat.PortMap
+
+	// runtime runs the container of the machine.
+	runtime runtime.Runtime
+	// timeouts bound the creation and the start of the machine.
+	timeouts Timeouts
+}
+
+// PortBinding is a host address and port a container port is published on.
+type PortBinding struct {
+	HostIP   string `json:"hostIP,omitempty"`
+	HostPort int    `json:"hostPort"`
 }
 
 // newMachine inits a new indexed Machine in the cluster.
 func newMachine(cluster *config.Cluster, machineSet *config.MachineSet, machine *config.Machine, i int) *Machine {
 	return &Machine{
+		machineSet:    machineSet.Name,
 		index:         i,
+		cluster:       cluster.Name,
 		spec:          machine,
 		containerName: f("%s-%s-"+machine.Name, cluster.Name, machineSet.Name, i),
 		machineName:   f("%s-"+machine.Name, machineSet.Name, i),
+		fqdn:          f("%s-"+machine.Name+".%s", machineSet.Name, i, cluster.MachineDomain()),
+		runtime:       runtime.Docker{},
+		timeouts:      DefaultTimeouts,
 	}
 }
 
-// CreateMachine creates and starts a new machine in the cluster.
-func (m *Machine) Create(c *config.Cluster, publicKey []byte) error {
+// CreateMachine creates and starts a new machine in the cluster. The public
+// keys to authorize are given per user.
+func (m *Machine) Create(ctx context.Context, c *config.Cluster, publicKeys map[string][]byte) error {
 	// Start the container.
-	utils.Logger.Infof("Creating machine: %s ...", m.containerName)
+	start := time.Now()
+	m.logger().Infof("Creating machine: %s ...", m.containerName)
 
 	if m.IsCreated() {
-		utils.Logger.Infof("Machine %s is already created...", m.containerName)
+		m.logger().Infof("Machine %s is already created...", m.containerName)
 		return nil
 	}
 
+	err := withTimeout(ctx, f("starting machine %s", m.machineName), m.timeouts.Start, func(ctx context.Context) error {
+		return m.createContainer(ctx, c)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Initial provisioning.
+	err = withTimeout(ctx, f("provisioning machine %s", m.machineName), m.timeouts.Provision, func(ctx context.Context) error {
+		return m.provision(ctx, publicKeys)
+	})
+	if err != nil {
+		return err
+	}
+	withOperation(m.logger(), "create", start).Infof("Created machine %s", m.machineName)
+	return nil
+}
+
+// createContainer creates the container of the machine, and starts it.
+func (m *Machine) createContainer(ctx context.Context, c *config.Cluster) error {
 	cmd := []string{"/sbin/init"}
 	if strings.TrimSpace(m.spec.Cmd) != "" {
 		cmd = strings.Split(strings.TrimSpace(m.spec.Cmd), " ")
@@ -99,25 +201,21 @@ func (m *Machine) Create(c *config.Cluster, publicKey []byte) error {
 
 	// create the actual Docker container
 	runArgs := m.generateContainerRunArgs(c.Name)
-	_, err := docker.Create(m.spec.Image,
-		runArgs,
-		cmd,
-	)
-	if err != nil {
+	if err := m.runtime.Create(ctx, m.spec.Image, runArgs, cmd); err != nil {
 		return err
 	}
 
 	if len(m.spec.Networks) > 1 {
 		for _, network := range m.spec.Networks[1:] {
-			utils.Logger.Infof("Connecting %s to the %s network...", m.machineName, network)
+			m.logger().Infof("Connecting %s to the %s network...", m.machineName, network)
 
 			// if default "bridge" network is specified, connect to it
 			if network == "bridge" {
-				if err := docker.ConnectNetwork(m.containerName, network); err != nil {
+				if err := m.runtime.ConnectNetwork(ctx, m.containerName, network); err != nil {
 					return err
 				}
 			} else {
-				if err := docker.ConnectNetworkWithAlias(m.containerName, network, m.machineName); err != nil {
+				if err := m.runtime.ConnectNetwork(ctx, m.containerName, network, m.machineName, m.fqdn); err != nil {
 					return err
 				}
 			}
@@ -125,21 +223,39 @@ func (m *Machine) Create(c *config.Cluster, publicKey []byte) error {
 	}
 
 	// start up the container
-	utils.Logger.Infof("Starting machine %s...", m.machineName)
-	if err := docker.Start(m.containerName); err != nil {
-		return err
-	}
+	m.logger().Infof("Starting machine %s...", m.machineName)
+	return m.runtime.Start(ctx, m.containerName)
+}
 
-	// Initial provisioning.
-	var keyPath = KEY_PATH_ROOT
-	if m.User() != "root" {
-		keyPath = f(KEY_PATH_NORMAL, m.User())
+// provision creates the users of a started machine, and authorizes their keys.
+func (m *Machine) provision(ctx context.Context, publicKeys map[string][]byte) error {
+	user := m.User()
+	listed := slices.ContainsFunc(m.spec.Users, func(u config.User) bool { return u.Name == user })
+	if user != "root" && !listed {
+		m.logger().Debugf("Creating user %s on machine %s if missing...", user, m.machineName)
+		if err := m.runShell(ctx, f(LOGIN_USER_SCRIPT, user, defaultShell)); err != nil {
+			return err
+		}
 	}
-	if err := containerRunShell(m.containerName, f(INIT_SCRIPT, m.User())); err != nil {
-		return err
+	for _, u := range m.spec.Users {
+		if u.Name == "root" {
+			continue
+		}
+		m.logger().Infof("Creating user %s on machine %s...", u.Name, m.machineName)
+		if err := m.runShell(ctx, userScript(&u)); err != nil {
+			return err
+		}
 	}
-	if err := copy(m.containerName, publicKey, keyPath); err != nil {
-		return err
+	for _, user := range m.Users() {
+		if err := m.runShell(ctx, f(INIT_SCRIPT, user)); err != nil {
+			return err
+		}
+		if len(publicKeys[user]) == 0 {
+			continue
+		}
+		if err := m.authorizeKeys(ctx, user, publicKeys[user], false); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -151,6 +267,7 @@ func (m *Machine) generateContainerRunArgs(cluster string) []string {
 		"-it",
 		"--label", "creator=vind",
 		"--label", f("cluster=%s", cluster),
+		"--label", f("machineSet=%s", m.machineSet),
 		"--label", f("index=%d", m.index),
 		"--name", m.containerName,
 		"--hostname", m.machineName,
@@ -194,10 +311,10 @@ func (m *Machine) generateContainerRunArgs(cluster string) []string {
 
 	if len(m.spec.Networks) > 0 {
 		network := m.spec.Networks[0]
-		utils.Logger.Infof("Connecting %s to the %s network...", m.machineName, network)
+		m.logger().Infof("Connecting %s to the %s network...", m.machineName, network)
 		runArgs = append(runArgs, "--network", m.spec.Networks[0])
 		if network != "bridge" {
-			runArgs = append(runArgs, "--network-alias", m.machineName)
+			runArgs = append(runArgs, "--network-alias", m.machineName, "--network-alias", m.fqdn)
 		}
 	}
 
@@ -205,132 +322,223 @@ func (m *Machine) generateContainerRunArgs(cluster string) []string {
 }
 
 // Delete deletes a Machine from the cluster.
-func (m *Machine) Delete() error {
+func (m *Machine) Delete(ctx context.Context) error {
 	if !m.IsCreated() {
-		utils.Logger.Infof("Machine %s hasn't been created", m.machineName)
+		m.logger().Infof("Machine %s hasn't been created", m.machineName)
 		return nil
 	}
 
 	if m.IsStarted() {
-		utils.Logger.Infof("Machine %s is started, stopping and deleting machine...", m.machineName)
-		err := docker.Kill("KILL", m.containerName)
-		if err != nil {
-			return err
-		}
-		cmd := exec.Command(
-			"docker", "rm", "--volumes",
-			m.containerName,
-		)
-		return cmd.Run()
-	}
-	utils.Logger.Infof("Deleting machine: %s ...", m.machineName)
-	cmd := exec.Command(
-		"docker", "rm", "--volumes",
-		m.containerName,
-	)
-	return cmd.Run()
-}
-
-// Start starts a Machine
-func (m *Machine) Start() error {
+		m.logger().Infof("Machine %s is started, stopping and deleting machine...", m.machineName)
+	} else {
+		m.logger().Infof("Deleting machine: %s ...", m.machineName)
+	}
+	start := time.Now()
+	if err := m.runtime.Remove(ctx, m.containerName); err != nil {
+		return err
+	}
+	withOperation(m.logger(), "delete", start).Infof("Deleted machine %s", m.machineName)
+	return nil
+}
+
+// Start starts a Machine, or fails with ErrNotCreated.
+func (m *Machine) Start(ctx context.Context) error {
 	if !m.IsCreated() {
-		utils.Logger.Infof("Machine %s hasn't been created...", m.machineName)
-		return nil
+		return fmt.Errorf("%w: %s", ErrNotCreated, m.machineName)
 	}
 	if m.IsStarted() {
-		utils.Logger.Infof("Machine %s is already started...", m.machineName)
+		m.logger().Infof("Machine %s is already started...", m.machineName)
 		return nil
 	}
-	utils.Logger.Infof("Starting machine: %s ...", m.machineName)
-
-	// Run command while sigs.k8s.io/kind/pkg/container/docker doesn't
-	// have a start command
-	cmd := exec.Command(
-		"docker", "start",
-		m.containerName,
-	)
-	return cmd.Run()
+	m.logger().Infof("Starting machine: %s ...", m.machineName)
+	start := time.Now()
+	err := withTimeout(ctx, f("starting machine %s", m.machineName), m.timeouts.Start, func(ctx context.Context) error {
+		return m.runtime.Start(ctx, m.containerName)
+	})
+	if err != nil {
+		return err
+	}
+	withOperation(m.logger(), "start", start).Infof("Started machine %s", m.machineName)
+	return nil
 }
 
-// Stop stops a Machine
-func (m *Machine) Stop() error {
+// Stop stops a Machine, or fails with ErrNotCreated.
+func (m *Machine) Stop(ctx context.Context) error {
 	if !m.IsCreated() {
-		utils.Logger.Infof("Machine %s hasn't been created...", m.containerName)
-		return nil
+		return fmt.Errorf("%w: %s", ErrNotCreated, m.machineName)
 	}
 	if !m.IsStarted() {
-		utils.Logger.Infof("Machine %s is already stopped...", m.containerName)
+		m.logger().Infof("Machine %s is already stopped...", m.containerName)
 		return nil
 	}
-	utils.Logger.Infof("Stopping machine: %s ...", m.containerName)
+	m.logger().Infof("Stopping machine: %s ...", m.containerName)
+	start := time.Now()
+	if err := m.runtime.Stop(ctx, m.containerName); err != nil {
+		return err
+	}
+	withOperation(m.logger(), "stop", start).Infof("Stopped machine %s", m.machineName)
+	return nil
+}
+
+// MachineName returns the name of the machine, which is also its hostname.
+func (m *Machine) MachineName() string {
+	return m.machineName
+}
+
+// ContainerName returns the name of the container of the machine, like
+// "cluster-node0".
+func (m *Machine) ContainerName() string {
+	return m.containerName
+}
+
+// MachineSet returns the name of the MachineSet of the machine.
+func (m *Machine) MachineSet() string {
+	return m.machineSet
+}
+
+// Index returns the index of the machine in its MachineSet.
+func (m *Machine) Index() int {
+	return m.index
+}
+
+// FQDN returns the fully qualified domain name of the machine, which all the
+// machines of the cluster resolve.
+func (m *Machine) FQDN() string {
+	return m.fqdn
+}
 
-	// Run command while sigs.k8s.io/kind/pkg/container/docker doesn't
-	// have a start command
-	cmd := exec.Command(
-		"docker", "stop",
-		m.containerName,
-	)
-	return cmd.Run()
+// Spec returns the specification of the machine, which must not be modified.
+func (m *Machine) Spec() *config.Machine {
+	return m.spec
 }
 
 // User gets the machine's OS user, defaults to root if not specified.
 func (m *Machine) User() string {
-	if m.spec.User == "" {
+	return userOf(m.spec)
+}
+
+// Users gets the machine's OS users to authorize public keys for, starting with
+// the machine's user.
+func (m *Machine) Users() []string {
+	users := []string{m.User()}
+	for user := range m.spec.UserPublicKeys {
+		if !slices.Contains(users, user) {
+			users = append(users, user)
+		}
+	}
+	for _, u := range m.spec.Users {
+		if !slices.Contains(users, u.Name) {
+			users = append(users, u.Name)
+		}
+	}
+	slices.Sort(users[1:])
+	return users
+}
+
+// userScript generates the script creating or updating the given user.
+func userScript(u *config.User) string {
+	uid := ""
+	if u.UID > 0 {
+		uid = strconv.Itoa(u.UID)
+	}
+	return f(USER_SCRIPT, u.Name, uid, u.Shell, defaultShell, strings.Join(u.Groups, ","), u.Sudo)
+}
+
+// userOf gets the user of a machine spec, defaults to root if not specified.
+func userOf(spec *config.Machine) string {
+	if spec.User == "" {
 		return defaultUser
 	}
-	return m.spec.User
+	return spec.User
+}
+
+// authorizedKeysPath returns the path of the authorized_keys file of a user.
+func (m *Machine) authorizedKeysPath(user string) string {
+	if user == "root" {
+		return KEY_PATH_ROOT
+	}
+	return f(KEY_PATH_NORMAL, user)
+}
+
+// authorizeKeys installs public keys into the authorized_keys file of a user.
+// The keys are merged into the already authorized ones, unless replace is set.
+func (m *Machine) authorizeKeys(ctx context.Context, user string, keys []byte, replace bool) error {
+	path := m.authorizedKeysPath(user)
+	var existing []byte
+	if !replace {
+		var err error
+		if existing, err = m.readFile(ctx, path); err != nil {
+			return err
+		}
+	}
+	return m.writeFile(ctx, mergeAuthorizedKeys(existing, keys), path, user+":", 0600)
+}
+
+// inspect returns the details of the container of the machine. Inspecting is
+// quick, so the queries of the machine state don't take a context.
+func (m *Machine) inspect() (*types.ContainerJSON, error) {
+	return m.runtime.Inspect(context.Background(), m.containerName)
 }
 
 // IsCreated returns if a machine is has been created. A created machine could
 // either be running or stopped.
 func (m *Machine) IsCreated() bool {
-	res, err := docker.Inspect(m.containerName, "{{.Name}}")
-	if err != nil {
-		return false
-	}
-	if len(res) > 0 && len(res[0]) > 0 {
-		return true
-	}
-	return false
+	_, err := m.inspect()
+	return err == nil
 }
 
 // IsStarted returns if a machine is currently started or not.
 func (m *Machine) IsStarted() bool {
-	res, _ := docker.Inspect(m.containerName, "{{.State.Running}}")
-	parsed, _ := strconv.ParseBool(strings.Trim(res[0], `'`))
-	return parsed
+	inspect, err := m.inspect()
+	return err == nil && inspect.State != nil && inspect.State.Running
 }
 
-// HostPort returns the host port corresponding to the given container port.
-func (m *Machine) HostPort(containerPort int) (int, error) {
+// HostPorts returns all the host bindings of the given container port and
+// protocol, e.g. both the IPv4 and IPv6 bindings of a published port.
+// The protocol defaults to "tcp" if empty.
+func (m *Machine) HostPorts(containerPort int, protocol string) ([]PortBinding, error) {
 	// Use the cached version first
-	if hostPort, ok := m.ports[containerPort]; ok {
-		return hostPort, nil
+	if m.portMap == nil {
+		inspect, err := m.inspect()
+		if err != nil {
+			return nil, errors.Wrap(err, "hostport: failed to inspect container")
+		}
+		m.portMap = inspect.NetworkSettings.Ports
 	}
+	return portBindings(m.portMap, containerPort, protocol)
+}
 
-	var hostPort int
-
-	// retrieve the specific port mapping using docker inspect
-	lines, err := docker.Inspect(m.containerName, fmt.Sprintf("{{(index (index .NetworkSettings.Ports \"%d/tcp\") 0).HostPort}}", containerPort))
+// HostPort returns the first host port corresponding to the given TCP container port.
+func (m *Machine) HostPort(containerPort int) (int, error) {
+	bindings, err := m.HostPorts(containerPort, "tcp")
 	if err != nil {
-		return -1, errors.Wrapf(err, "hostport: failed to inspect container: %v", lines)
-	}
-	if len(lines) != 1 {
-		return -1, errors.Errorf("hostport: should only be one line, got %d lines", len(lines))
+		return -1, err
 	}
+	return bindings[0].HostPort, nil
+}
 
-	port := strings.Replace(lines[0], "'", "", -1)
-	if hostPort, err = strconv.Atoi(port); err != nil {
-		return -1, errors.Wrap(err, "hostport: failed to parse string to int")
+// portBindings looks up the host bindings of a container port in the port map.
+func portBindings(ports nat.PortMap, containerPort int, protocol string) ([]PortBinding, error) {
+	if protocol == "" {
+		protocol = "tcp"
 	}
-
-	if m.ports == nil {
-		m.ports = make(map[int]int)
+	p, err := nat.NewPort(protocol, strconv.Itoa(containerPort))
+	if err != nil {
+		return nil, errors.Wrap(err, "hostport: invalid port")
+	}
+	if len(ports[p]) < 1 {
+		return nil, errors.Errorf("hostport: container port %s is not published", p)
 	}
 
-	// Cache the result
-	m.ports[containerPort] = hostPort
-	return hostPort, nil
+	bindings := make([]PortBinding, 0, len(ports[p]))
+	for _, b := range ports[p] {
+		hostPort, err := strconv.Atoi(b.HostPort)
+		if err != nil {
+			return nil, errors.Wrap(err, "hostport: failed to parse string to int")
+		}
+		bindings = append(bindings, PortBinding{HostIP: b.HostIP, HostPort: hostPort})
+	}
+	return bindings, nil
 }
 
 func (m *Machine) networks() ([]*RuntimeNetwork, error) {
@@ -338,11 +546,11 @@ func (m *Machine) networks() ([]*RuntimeNetwork, error) {
 		return m.runtimeNetworks, nil
 	}
 
-	var networks map[string]*network.EndpointSettings
-	if err := docker.InspectObject(m.containerName, ".NetworkSettings.Networks", &networks); err != nil {
+	inspect, err := m.inspect()
+	if err != nil {
 		return nil, err
 	}
-	m.runtimeNetworks = NewRuntimeNetworks(networks)
+	m.runtimeNetworks = NewRuntimeNetworks(inspect.NetworkSettings.Networks)
 	return m.runtimeNetworks, nil
 }
 
@@ -350,20 +558,29 @@ func (m *Machine) dockerStatus(s *MachineStatus) error {
 	var ports []port
 	if m.IsCreated() {
 		for _, v := range m.spec.PortMappings {
-			hPort, err := m.HostPort(int(v.ContainerPort))
+			protocol := v.Protocol
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			bindings, err := m.HostPorts(int(v.ContainerPort), protocol)
 			if err != nil {
-				hPort = 0
+				ports = append(ports, port{Host: 0, Guest: int(v.ContainerPort), Protocol: protocol})
+				continue
 			}
-			p := port{
-				Host:  hPort,
-				Guest: int(v.ContainerPort),
+			for _, b := range bindings {
+				p := port{
+					Host:     b.HostPort,
+					Guest:    int(v.ContainerPort),
+					Protocol: protocol,
+					Address:  b.HostIP,
+				}
+				ports = append(ports, p)
 			}
-			ports = append(ports, p)
 		}
 	}
 	if len(ports) < 1 {
 		for _, p := range m.spec.PortMappings {
-			ports = append(ports, port{Host: 0, Guest: int(p.ContainerPort)})
+			ports = append(ports, port{Host: 0, Guest: int(p.ContainerPort), Protocol: p.Protocol})
 		}
 	}
 	s.Ports = ports
@@ -381,7 +598,6 @@ func (m *Machine) Status() *MachineStatus {
 	s.Command = m.spec.Cmd
 	s.Spec = m.spec
 	s.MachineName = m.machineName
-	s.IP = strings.Join(m.IP(), ",")
 	state := NotCreated
 
 	if m.IsCreated() {
@@ -393,6 +609,7 @@ func (m *Machine) Status() *MachineStatus {
 	s.State = state
 
 	_ = m.dockerStatus(&s)
+	s.IP = strings.Join(m.IP(), ",")
 
 	return &s
 }
@@ -415,7 +632,7 @@ func (m *Machine) AutoCdTo() string {
 		if volume.Type == "bind" && volume.Destination == "/host" {
 			pwd, err := os.Getwd()
 			if err != nil {
-				utils.Logger.Warn("can't get current working directory: %w", err)
+				m.logger().Warn("can't get current working directory: %w", err)
 			}
 			return fmt.Sprintf("%s%s", "/host", pwd)
 		}
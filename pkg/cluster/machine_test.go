@@ -0,0 +1,91 @@
+/*
+Copyright © 2019-2023 footloose developers
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortBindings(t *testing.T) {
+	ports := nat.PortMap{
+		"22/tcp": []nat.PortBinding{
+			{HostIP: "0.0.0.0", HostPort: "32768"},
+			{HostIP: "::", HostPort: "32768"},
+		},
+		"53/udp": []nat.PortBinding{
+			{HostIP: "127.0.0.1", HostPort: "5353"},
+		},
+		"80/tcp": nil,
+	}
+
+	t.Run("IPv4 and IPv6", func(t *testing.T) {
+		bindings, err := portBindings(ports, 22, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []PortBinding{
+			{HostIP: "0.0.0.0", HostPort: 32768},
+			{HostIP: "::", HostPort: 32768},
+		}, bindings)
+	})
+
+	t.Run("UDP", func(t *testing.T) {
+		bindings, err := portBindings(ports, 53, "udp")
+		assert.NoError(t, err)
+		assert.Equal(t, []PortBinding{{HostIP: "127.0.0.1", HostPort: 5353}}, bindings)
+
+		_, err = portBindings(ports, 53, "tcp")
+		assert.Error(t, err)
+	})
+
+	t.Run("Not published", func(t *testing.T) {
+		_, err := portBindings(ports, 80, "tcp")
+		assert.Error(t, err)
+	})
+}
+
+func TestSSHEndpoint(t *testing.T) {
+	host, port := sshEndpoint([]PortBinding{{HostIP: "0.0.0.0", HostPort: 2222}, {HostIP: "::", HostPort: 2222}})
+	assert.Equal(t, "localhost", host)
+	assert.Equal(t, 2222, port)
+
+	host, port = sshEndpoint([]PortBinding{{HostIP: "127.0.0.2", HostPort: 2223}})
+	assert.Equal(t, "127.0.0.2", host)
+	assert.Equal(t, 2223, port)
+}
+
+func TestUsers(t *testing.T) {
+	spec := &config.Machine{
+		Name:           "node%d",
+		User:           "ubuntu",
+		UserPublicKeys: map[string][]string{"root": {"alice"}},
+		Users: []config.User{
+			{Name: "ubuntu", Sudo: config.SudoNoPasswd},
+			{Name: "alice", UID: 1500, Groups: []string{"docker", "wheel"}, Shell: "/bin/zsh"},
+		},
+	}
+	m := newMachine(&config.Cluster{Name: "cluster"}, &config.MachineSet{Name: "test"}, spec, 0)
+	assert.Equal(t, []string{"ubuntu", "alice", "root"}, m.Users())
+
+	script := userScript(&spec.Users[0])
+	assert.Contains(t, script, "u=ubuntu\nuid=\nshell=\ndefault_shell=/bin/bash\ngroups=\nsudo=nopasswd\n")
+
+	script = userScript(&spec.Users[1])
+	assert.Contains(t, script, "u=alice\nuid=1500\nshell=/bin/zsh\ndefault_shell=/bin/bash\ngroups=docker,wheel\nsudo=\n")
+}
@@ -0,0 +1,444 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FAULTS_CHAIN is the iptables chain of the partitions in the machines.
+const FAULTS_CHAIN = "VIND-FAULTS"
+
+// maxNetworkFaults is how many netem faults a machine can have: the prio
+// qdisc has 16 bands at most, and the first 3 are for the normal traffic.
+const maxNetworkFaults = 13
+
+// RESET_FAULTS_SCRIPT removes the tc and iptables rules of the faults.
+const RESET_FAULTS_SCRIPT = `if command -v tc >/dev/null 2>&1; then
+  for dev in $(ls /sys/class/net); do tc qdisc del dev "$dev" root 2>/dev/null || true; done
+fi
+if command -v iptables >/dev/null 2>&1 && iptables -n -L VIND-FAULTS >/dev/null 2>&1; then
+  while iptables -D INPUT -j VIND-FAULTS 2>/dev/null; do :; done
+  while iptables -D OUTPUT -j VIND-FAULTS 2>/dev/null; do :; done
+  iptables -F VIND-FAULTS
+  iptables -X VIND-FAULTS
+fi`
+
+// Netem is an impairment of the traffic, as in the netem queueing discipline
+// of tc.
+type Netem struct {
+	// Delay of the packets, like "100ms"
+	Delay string `json:"delay,omitempty"`
+	// Jitter of the delay, like "10ms"
+	Jitter string `json:"jitter,omitempty"`
+	// Loss is the percentage of dropped packets, like "5%"
+	Loss string `json:"loss,omitempty"`
+	// Duplicate is the percentage of duplicated packets
+	Duplicate string `json:"duplicate,omitempty"`
+	// Corrupt is the percentage of packets with a flipped bit
+	Corrupt string `json:"corrupt,omitempty"`
+	// Rate limits the bandwidth, like "1mbit"
+	Rate string `json:"rate,omitempty"`
+}
+
+// validRate matches the tc rates, in bits or bytes per second.
+var validRate = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[kmgt]?(bit|bps)$`)
+
+// Validate checks the impairment.
+func (n Netem) Validate() error {
+	if n == (Netem{}) {
+		return errors.New("netem: no delay, loss, duplicate, corrupt or rate given")
+	}
+	for _, d := range []struct{ name, value string }{{"delay", n.Delay}, {"jitter", n.Jitter}} {
+		if d.value == "" {
+			continue
+		}
+		if v, err := time.ParseDuration(d.value); err != nil || v < 0 {
+			return fmt.Errorf("netem: %s %q is not a valid duration", d.name, d.value)
+		}
+	}
+	if n.Jitter != "" && n.Delay == "" {
+		return errors.New("netem: jitter needs a delay")
+	}
+	for _, p := range []struct{ name, value string }{{"loss", n.Loss}, {"duplicate", n.Duplicate}, {"corrupt", n.Corrupt}} {
+		if p.value == "" {
+			continue
+		}
+		if _, err := parsePercent(p.value); err != nil {
+			return errors.Wrapf(err, "netem: %s", p.name)
+		}
+	}
+	if n.Rate != "" && !validRate.MatchString(strings.ToLower(n.Rate)) {
+		return fmt.Errorf("netem: rate %q is not valid, it should be like 1mbit or 100kbps", n.Rate)
+	}
+	return nil
+}
+
+// parsePercent parses a percentage like "5%" or "5".
+func parsePercent(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil || v < 0 || v > 100 {
+		return 0, fmt.Errorf("%q is not a valid percentage", s)
+	}
+	return v, nil
+}
+
+// tcTime formats a duration for tc, which doesn't understand "1m0s".
+func tcTime(s string) string {
+	d, _ := time.ParseDuration(s)
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64) + "ms"
+}
+
+// args returns the netem arguments of tc.
+func (n Netem) args() []string {
+	var args []string
+	if n.Delay != "" {
+		args = append(args, "delay", tcTime(n.Delay))
+		if n.Jitter != "" {
+			args = append(args, tcTime(n.Jitter))
+		}
+	}
+	for _, p := range []struct{ name, value string }{{"loss", n.Loss}, {"duplicate", n.Duplicate}, {"corrupt", n.Corrupt}} {
+		if p.value != "" {
+			v, _ := parsePercent(p.value)
+			args = append(args, p.name, strconv.FormatFloat(v, 'f', -1, 64)+"%")
+		}
+	}
+	if n.Rate != "" {
+		args = append(args, "rate", strings.ToLower(n.Rate))
+	}
+	return args
+}
+
+// String formats the impairment like "delay 100ms loss 5%".
+func (n Netem) String() string {
+	return strings.Join(n.args(), " ")
+}
+
+// machineNames returns the sorted names of the machines.
+func machineNames(machines []*Machine) []string {
+	names := make([]string, len(machines))
+	for i, m := range machines {
+		names[i] = m.machineName
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Netem impairs the traffic sent by the machines, to the given machines only
+// if any, replacing the previous impairment of the same traffic. The fault is
+// recorded in the cluster state, and applied again when machines start.
+func (c *Cluster) Netem(ctx context.Context, machines []*Machine, netem Netem, to []*Machine) error {
+	if err := netem.Validate(); err != nil {
+		return err
+	}
+	toNames := machineNames(to)
+	var err error
+	updateErr := c.updateState(func(s *State) {
+		faults := append([]NetworkFault{}, s.NetworkFaults...)
+		for _, name := range machineNames(machines) {
+			count := 0
+			kept := faults[:0]
+			for _, fault := range faults {
+				if fault.MachineName == name && strings.Join(fault.To, ",") == strings.Join(toNames, ",") {
+					continue
+				}
+				if fault.MachineName == name {
+					count++
+				}
+				kept = append(kept, fault)
+			}
+			if count >= maxNetworkFaults {
+				err = fmt.Errorf("netem: machine %s can't have more than %d faults", name, maxNetworkFaults)
+				return
+			}
+			faults = append(kept, NetworkFault{MachineName: name, Netem: netem, To: toNames, Created: time.Now()})
+		}
+		s.NetworkFaults = faults
+	})
+	if err != nil {
+		return err
+	}
+	if updateErr != nil {
+		return updateErr
+	}
+	return c.applyFaults(ctx, nameSet(machineNames(machines)))
+}
+
+// Partition drops the traffic between the machines of different groups,
+// until the faults are reset. The partition is recorded in the cluster
+// state, and applied again when machines start.
+func (c *Cluster) Partition(ctx context.Context, groups [][]*Machine) error {
+	if len(groups) < 2 {
+		return errors.New("partition: at least two groups of machines are needed")
+	}
+	partition := Partition{Created: time.Now()}
+	var all []string
+	seen := map[string]bool{}
+	for _, group := range groups {
+		names := machineNames(group)
+		for _, name := range names {
+			if seen[name] {
+				return fmt.Errorf("partition: machine %s is in several groups", name)
+			}
+			seen[name] = true
+		}
+		partition.Groups = append(partition.Groups, names)
+		all = append(all, names...)
+	}
+	err := c.updateState(func(s *State) {
+		s.Partitions = append(s.Partitions, partition)
+	})
+	if err != nil {
+		return err
+	}
+	return c.applyFaults(ctx, nameSet(all))
+}
+
+// ResetFaults removes the faults of the machines, which are the netem faults
+// of their traffic and the partitions they're in, or all the faults if no
+// machine is given. It returns how many faults were removed.
+func (c *Cluster) ResetFaults(ctx context.Context, machines []*Machine) (int, error) {
+	reset := nameSet(machineNames(machines))
+	all := len(machines) == 0
+	affected := map[string]bool{}
+	removed := 0
+	err := c.updateState(func(s *State) {
+		faults := s.NetworkFaults[:0]
+		for _, fault := range s.NetworkFaults {
+			if all || reset[fault.MachineName] {
+				affected[fault.MachineName] = true
+				removed++
+				continue
+			}
+			faults = append(faults, fault)
+		}
+		s.NetworkFaults = faults
+
+		partitions := s.Partitions[:0]
+		for _, partition := range s.Partitions {
+			members := nameSet(partition.names())
+			if !all && !intersects(members, reset) {
+				partitions = append(partitions, partition)
+				continue
+			}
+			for name := range members {
+				affected[name] = true
+			}
+			removed++
+		}
+		s.Partitions = partitions
+	})
+	if err != nil {
+		return 0, err
+	}
+	if all {
+		// clean up the rules of the faults recorded by another state too
+		affected = nil
+	}
+	return removed, c.applyFaults(ctx, affected)
+}
+
+// refreshFaults applies the recorded faults again, as the rules are lost when
+// machines restart, and the IPs they drop may have changed.
+func (c *Cluster) refreshFaults(ctx context.Context) {
+	state, err := c.State()
+	if err == nil && len(state.NetworkFaults) == 0 && len(state.Partitions) == 0 {
+		return
+	}
+	if err == nil {
+		err = c.applyFaults(ctx, nil)
+	}
+	if err != nil {
+		c.logger().Warnf("Can't apply the network faults to the machines: %v", err)
+	}
+}
+
+// forgetFaults removes the recorded faults, once the machines are deleted.
+func (c *Cluster) forgetFaults() error {
+	state, err := c.State()
+	if err != nil || (len(state.NetworkFaults) == 0 && len(state.Partitions) == 0) {
+		return err
+	}
+	return c.updateState(func(s *State) {
+		s.NetworkFaults = nil
+		s.Partitions = nil
+	})
+}
+
+// applyFaults replaces the rules of the running machines among the named
+// ones, or of all of them if names is nil, by those of the recorded faults.
+func (c *Cluster) applyFaults(ctx context.Context, names map[string]bool) error {
+	state, err := c.State()
+	if err != nil {
+		return err
+	}
+	running, err := c.runningMachines()
+	if err != nil {
+		return err
+	}
+	ips := map[string][]string{}
+	var machines []*Machine
+	for _, m := range running {
+		for _, ip := range m.IP() {
+			if ip != "" {
+				ips[m.machineName] = append(ips[m.machineName], ip)
+			}
+		}
+		if names == nil || names[m.machineName] {
+			machines = append(machines, m)
+		}
+	}
+	return forMachinesInParallel(machines, func(m *Machine) error {
+		m.logger().Infof("Applying the network faults of machine %s...", m.machineName)
+		script := faultsScript(m.machineName, state, ips)
+		if err := m.run(ctx, "/bin/sh", "-c", script); err != nil {
+			return errors.Wrapf(err, "can't apply the network faults of machine %s", m.machineName)
+		}
+		return nil
+	})
+}
+
+// faultsScript returns the script replacing the rules of the machine by
+// those of its faults, given the IPs of the running machines.
+//
+// The traffic of each netem fault goes to its own band of a prio qdisc, the
+// traffic to given machines being selected by their IPs first, and the
+// partitions drop the traffic from and to the other groups in an iptables
+// chain.
+func faultsScript(machineName string, s *State, ips map[string][]string) string {
+	lines := []string{"set -e", RESET_FAULTS_SCRIPT}
+
+	type band struct {
+		netem Netem
+		all   bool
+		dsts  []string
+	}
+	var bands []band
+	for _, fault := range s.NetworkFaults {
+		if fault.MachineName != machineName {
+			continue
+		}
+		b := band{netem: fault.Netem, all: len(fault.To) == 0}
+		for _, to := range fault.To {
+			if to != machineName {
+				b.dsts = append(b.dsts, ips[to]...)
+			}
+		}
+		if b.all || len(b.dsts) > 0 {
+			bands = append(bands, b)
+		}
+	}
+	if len(bands) > 0 {
+		lines = append(lines,
+			`command -v tc >/dev/null 2>&1 || { echo "tc is not installed" >&2; exit 1; }`,
+			`for dev in $(ls /sys/class/net); do`,
+			`  [ "$dev" != lo ] || continue`,
+			`  case "$(cat /sys/class/net/$dev/operstate)" in up|unknown) ;; *) continue ;; esac`,
+			f(`  tc qdisc add dev "$dev" root handle 1: prio bands %d priomap 1 2 2 2 1 2 0 0 1 1 1 1 1 1 1 1`, 3+len(bands)))
+		for i, b := range bands {
+			class := i + 4
+			lines = append(lines, f(`  tc qdisc add dev "$dev" parent 1:%x handle %x0: netem %s`, class, class, b.netem))
+			if b.all {
+				lines = append(lines, f(`  tc filter add dev "$dev" parent 1: protocol all prio 2 u32 match u32 0 0 flowid 1:%x`, class))
+			}
+			for _, dst := range b.dsts {
+				lines = append(lines, f(`  tc filter add dev "$dev" parent 1: protocol ip prio 1 u32 match ip dst %s/32 flowid 1:%x`, dst, class))
+			}
+		}
+		lines = append(lines, "done")
+	}
+
+	var drops []string
+	seen := map[string]bool{}
+	for _, partition := range s.Partitions {
+		for _, peer := range partition.peers(machineName) {
+			for _, ip := range ips[peer] {
+				if !seen[ip] {
+					seen[ip] = true
+					drops = append(drops, ip)
+				}
+			}
+		}
+	}
+	if len(drops) > 0 {
+		lines = append(lines,
+			`command -v iptables >/dev/null 2>&1 || { echo "iptables is not installed" >&2; exit 1; }`,
+			"iptables -N "+FAULTS_CHAIN,
+			"iptables -I INPUT -j "+FAULTS_CHAIN,
+			"iptables -I OUTPUT -j "+FAULTS_CHAIN)
+		for _, ip := range drops {
+			lines = append(lines,
+				f("iptables -A %s -s %s -j DROP", FAULTS_CHAIN, ip),
+				f("iptables -A %s -d %s -j DROP", FAULTS_CHAIN, ip))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// names returns the names of the machines of the partition.
+func (p Partition) names() []string {
+	var names []string
+	for _, group := range p.Groups {
+		names = append(names, group...)
+	}
+	return names
+}
+
+// peers returns the machines of the other groups than the one of the
+// machine, or nothing if it's not in the partition.
+func (p Partition) peers(machineName string) []string {
+	var peers []string
+	in := false
+	for _, group := range p.Groups {
+		if nameSet(group)[machineName] {
+			in = true
+			continue
+		}
+		peers = append(peers, group...)
+	}
+	if !in {
+		return nil
+	}
+	return peers
+}
+
+// nameSet returns the set of the names.
+func nameSet(names []string) map[string]bool {
+	set := map[string]bool{}
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// intersects returns true if the sets have a common name.
+func intersects(a, b map[string]bool) bool {
+	for name := range a {
+		if b[name] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetem(t *testing.T) {
+	tests := []struct {
+		name  string
+		netem Netem
+		valid bool
+		tc    string
+	}{
+		{"delay", Netem{Delay: "100ms", Jitter: "10ms"}, true, "delay 100ms 10ms"},
+		{"minute delay", Netem{Delay: "1m"}, true, "delay 60000ms"},
+		{"loss", Netem{Loss: "5%", Duplicate: "1", Corrupt: "0.5%"}, true, "loss 5% duplicate 1% corrupt 0.5%"},
+		{"rate", Netem{Rate: "1Mbit"}, true, "rate 1mbit"},
+		{"empty", Netem{}, false, ""},
+		{"bad delay", Netem{Delay: "100"}, false, ""},
+		{"jitter only", Netem{Jitter: "10ms"}, false, ""},
+		{"bad loss", Netem{Loss: "120%"}, false, ""},
+		{"bad rate", Netem{Rate: "fast"}, false, ""},
+	}
+	for _, ntest := range tests {
+		t.Run(ntest.name, func(t *testing.T) {
+			err := ntest.netem.Validate()
+			if !ntest.valid {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, ntest.tc, ntest.netem.String())
+		})
+	}
+}
+
+func TestPartitionPeers(t *testing.T) {
+	p := Partition{Groups: [][]string{{"node0", "node1"}, {"node2"}, {"node3"}}}
+	assert.Equal(t, "node0,node1 | node2 | node3", p.String())
+	assert.Equal(t, []string{"node2", "node3"}, p.peers("node0"))
+	assert.Equal(t, []string{"node0", "node1", "node3"}, p.peers("node2"))
+	assert.Nil(t, p.peers("node4"))
+}
+
+func TestFaultsScript(t *testing.T) {
+	ips := map[string][]string{"node0": {"10.0.0.2"}, "node1": {"10.0.0.3"}, "node2": {"10.0.0.4", "10.1.0.4"}}
+	state := &State{
+		NetworkFaults: []NetworkFault{
+			{MachineName: "node0", Netem: Netem{Delay: "100ms"}},
+			{MachineName: "node0", Netem: Netem{Loss: "5%"}, To: []string{"node2"}},
+			// not running
+			{MachineName: "node0", Netem: Netem{Loss: "50%"}, To: []string{"node3"}},
+		},
+		Partitions: []Partition{{Groups: [][]string{{"node0", "node1"}, {"node2"}}}},
+	}
+
+	script := faultsScript("node0", state, ips)
+	assert.Contains(t, script, RESET_FAULTS_SCRIPT)
+	assert.Contains(t, script, `tc qdisc add dev "$dev" root handle 1: prio bands 5 `)
+	assert.Contains(t, script, `tc qdisc add dev "$dev" parent 1:4 handle 40: netem delay 100ms`)
+	assert.Contains(t, script, `tc filter add dev "$dev" parent 1: protocol all prio 2 u32 match u32 0 0 flowid 1:4`)
+	assert.Contains(t, script, `tc qdisc add dev "$dev" parent 1:5 handle 50: netem loss 5%`)
+	assert.Contains(t, script, `match ip dst 10.0.0.4/32 flowid 1:5`)
+	assert.Contains(t, script, `match ip dst 10.1.0.4/32 flowid 1:5`)
+	assert.NotContains(t, script, "loss 50%")
+	assert.Contains(t, script, "iptables -A VIND-FAULTS -s 10.0.0.4 -j DROP")
+	assert.Contains(t, script, "iptables -A VIND-FAULTS -d 10.1.0.4 -j DROP")
+	assert.NotContains(t, script, "10.0.0.3 -j DROP")
+
+	// the other side of the partition, without netem
+	script = faultsScript("node2", state, ips)
+	assert.NotContains(t, script, "tc qdisc add")
+	assert.Contains(t, script, "iptables -A VIND-FAULTS -s 10.0.0.2 -j DROP")
+	assert.Contains(t, script, "iptables -A VIND-FAULTS -s 10.0.0.3 -j DROP")
+
+	// only the reset without faults
+	assert.Equal(t, "set -e\n"+RESET_FAULTS_SCRIPT+"\n", faultsScript("node3", state, ips))
+}
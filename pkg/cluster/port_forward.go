@@ -0,0 +1,249 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/brightzheng100/vind/pkg/proxy"
+	"github.com/pkg/errors"
+)
+
+// Transports used by PortForward to reach the machines.
+const (
+	// ForwardViaAuto reaches the machines by IP on Linux, where the Docker
+	// networks are routable from the host, and through docker exec elsewhere.
+	ForwardViaAuto = "auto"
+	// ForwardViaIP connects to the IP of the machine in its first network.
+	ForwardViaIP = "ip"
+	// ForwardViaExec tunnels the connections over docker exec, with nc. Only
+	// TCP is supported.
+	ForwardViaExec = "exec"
+)
+
+// PortForwardOptions are the options of the port forwards.
+type PortForwardOptions struct {
+	// Via is the transport to the machine, ForwardViaAuto if empty.
+	Via string
+	// Log is the log file of the process, when it runs in the background.
+	Log string
+}
+
+// PortForward forwards the ports of the host to the machine, until the
+// context is done. The forward is recorded in the cluster state meanwhile.
+func (c *Cluster) PortForward(ctx context.Context, machine *Machine, forwards []proxy.Forward, opts PortForwardOptions) error {
+	if !machine.IsStarted() {
+		return fmt.Errorf("machine %s is not running", machine.machineName)
+	}
+	via := forwardVia(opts.Via)
+	var dial proxy.Dialer
+	switch via {
+	case ForwardViaIP:
+		ip, err := machine.firstIP()
+		if err != nil {
+			return err
+		}
+		dial = proxy.NetDialer(ip)
+	case ForwardViaExec:
+		for _, f := range forwards {
+			if f.Protocol != "tcp" {
+				return fmt.Errorf("can't forward %s through docker exec, only tcp is supported", f)
+			}
+		}
+		dial = execDialer(ctx, machine)
+	default:
+		return fmt.Errorf("unknown port forward transport '%s', expected one of: %s, %s, %s", via, ForwardViaAuto, ForwardViaIP, ForwardViaExec)
+	}
+
+	if c.dryRun {
+		for _, f := range forwards {
+			machine.logger().Infof("Dry run: forwarding from %s -> %s:%d/%s via %s", f.HostAddress(), machine.machineName, f.ContainerPort, f.Protocol, via)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var serves []func() error
+	for i := range forwards {
+		f := &forwards[i]
+		switch f.Protocol {
+		case "udp":
+			conn, err := net.ListenPacket("udp", f.HostAddress())
+			if err != nil {
+				return errors.Wrapf(err, "can't forward %s", f)
+			}
+			defer conn.Close()
+			f.HostPort = conn.LocalAddr().(*net.UDPAddr).Port
+			serves = append(serves, func() error { return proxy.ServeUDP(ctx, conn, f.ContainerPort, dial) })
+		default:
+			listener, err := net.Listen("tcp", f.HostAddress())
+			if err != nil {
+				return errors.Wrapf(err, "can't forward %s", f)
+			}
+			defer listener.Close()
+			f.HostPort = listener.Addr().(*net.TCPAddr).Port
+			serves = append(serves, func() error { return proxy.ServeTCP(ctx, listener, f.ContainerPort, dial) })
+		}
+		machine.logger().Infof("Forwarding from %s -> %s:%d/%s via %s", f.HostAddress(), machine.machineName, f.ContainerPort, f.Protocol, via)
+	}
+
+	pid := os.Getpid()
+	// unsupported on some platforms, where the pid is enough
+	processStart, _ := exec.ProcessStart(pid)
+	err := c.updateState(func(s *State) {
+		s.PortForwards = append(s.PortForwards, PortForward{
+			MachineName:  machine.machineName,
+			PID:          pid,
+			Via:          via,
+			Forwards:     forwards,
+			Started:      time.Now(),
+			ProcessStart: processStart,
+			Log:          opts.Log,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	defer c.updateState(func(s *State) {
+		forwards := s.PortForwards[:0]
+		for _, pf := range s.PortForwards {
+			if pf.PID != pid {
+				forwards = append(forwards, pf)
+			}
+		}
+		s.PortForwards = forwards
+	})
+
+	errs := make(chan error, len(serves))
+	for _, serve := range serves {
+		go func() {
+			errs <- serve()
+		}()
+	}
+	// the first failure stops all the forwards
+	err = <-errs
+	cancel()
+	for range serves[1:] {
+		if e := <-errs; err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// forwardVia resolves the automatic transport to the machines.
+func forwardVia(via string) string {
+	if via != "" && via != ForwardViaAuto {
+		return via
+	}
+	if runtime.GOOS == "linux" {
+		return ForwardViaIP
+	}
+	return ForwardViaExec
+}
+
+// StopPortForwards stops the recorded port forwards of the machine, or of
+// all the machines if machineName is empty, and returns how many were
+// stopped.
+func (c *Cluster) StopPortForwards(machineName string) (int, error) {
+	state, err := c.State()
+	if err != nil {
+		return 0, err
+	}
+	stopped := 0
+	for _, pf := range state.PortForwards {
+		if machineName != "" && pf.MachineName != machineName {
+			continue
+		}
+		if c.dryRun {
+			c.logger().Infof("Dry run: stopping port forward of machine %s (pid %d)", pf.MachineName, pf.PID)
+			stopped++
+			continue
+		}
+		c.logger().Infof("Stopping port forward of machine %s (pid %d)", pf.MachineName, pf.PID)
+		if err := exec.Interrupt(pf.PID); err != nil {
+			return stopped, errors.Wrapf(err, "can't stop port forward %d", pf.PID)
+		}
+		stopped++
+	}
+	return stopped, nil
+}
+
+// firstIP returns the IP of the machine in its first network.
+func (m *Machine) firstIP() (string, error) {
+	networks, err := m.networks()
+	if err != nil {
+		return "", err
+	}
+	for _, network := range networks {
+		if network.IP != "" {
+			return network.IP, nil
+		}
+	}
+	return "", fmt.Errorf("machine %s has no IP", m.machineName)
+}
+
+// execDialer returns a dialer tunnelling the TCP connections to the ports of
+// the machine over an exec of nc in it.
+func execDialer(ctx context.Context, m *Machine) proxy.Dialer {
+	return func(network string, port int) (io.ReadWriteCloser, error) {
+		if network != "tcp" {
+			return nil, fmt.Errorf("can't dial %s through docker exec", network)
+		}
+		inReader, inWriter := io.Pipe()
+		outReader, outWriter := io.Pipe()
+		cmd := m.runtime.Cmder(ctx, m.containerName).Command("nc", "localhost", strconv.Itoa(port))
+		cmd.SetStdin(inReader)
+		cmd.SetStdout(outWriter)
+		go func() {
+			err := cmd.Run()
+			if err == nil {
+				err = io.EOF
+			}
+			outWriter.CloseWithError(err)
+			inReader.CloseWithError(err)
+		}()
+		return &execConn{stdin: inWriter, stdout: outReader}, nil
+	}
+}
+
+// execConn is a connection over the stdio of a docker exec process.
+type execConn struct {
+	stdin  *io.PipeWriter
+	stdout *io.PipeReader
+}
+
+func (c *execConn) Read(p []byte) (int, error) {
+	return c.stdout.Read(p)
+}
+
+func (c *execConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+func (c *execConn) Close() error {
+	c.stdin.Close()
+	return c.stdout.Close()
+}
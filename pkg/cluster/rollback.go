@@ -0,0 +1,138 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// rollbackTimeout bounds the rollback of a failed creation, which goes on
+// when the creation is interrupted.
+const rollbackTimeout = 2 * time.Minute
+
+// CreateOptions are the options of Create.
+type CreateOptions struct {
+	// Atomic removes what the creation created, the machines and the keys,
+	// if it fails. It then fails with a *RollbackError.
+	Atomic bool
+}
+
+// RollbackError is the error of an atomic creation which failed, and was
+// rolled back.
+type RollbackError struct {
+	// Err is the error which failed the creation.
+	Err error
+	// Removed are the machines and the files removed by the rollback.
+	Removed []string
+	// Left are the machines and the files the rollback couldn't remove, with
+	// why.
+	Left map[string]error
+}
+
+func (e *RollbackError) Error() string {
+	var summary []string
+	if len(e.Removed) > 0 {
+		summary = append(summary, "removed "+strings.Join(e.Removed, ", "))
+	} else {
+		summary = append(summary, "nothing to remove")
+	}
+	for _, name := range e.left() {
+		summary = append(summary, f("couldn't remove %s: %v", name, e.Left[name]))
+	}
+	return f("%v (rolled back: %s)", e.Err, strings.Join(summary, "; "))
+}
+
+// left returns the names of what was left, sorted.
+func (e *RollbackError) left() []string {
+	names := make([]string, 0, len(e.Left))
+	for name := range e.Left {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Unwrap returns the error which failed the creation, for errors.Is.
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// creation records what a creation creates, to roll it back.
+type creation struct {
+	// machines are the machines created, in their creation order.
+	machines []*Machine
+	// files are the files which didn't exist before the creation.
+	files []string
+}
+
+// newCreation starts recording a creation of the cluster.
+func (c *Cluster) newCreation() *creation {
+	var files []string
+	candidates := []string{c.KnownHostsPath(), c.caPath(), c.caPath() + ".pub"}
+	if path := c.privateKeyPath(); path != "" {
+		candidates = append(candidates, path, path+".pub")
+	}
+	for _, path := range candidates {
+		if !fileExists(path) {
+			files = append(files, path)
+		}
+	}
+	return &creation{files: files}
+}
+
+// rollback removes the machines and the files of the failed creation,
+// whether its context is done or not.
+func (c *Cluster) rollback(cr *creation, err error) *RollbackError {
+	c.logger().Warnf("Creation of cluster %s failed, rolling back: %v", c.Name(), err)
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+
+	rollbackErr := &RollbackError{Err: err, Left: map[string]error{}}
+	for i := len(cr.machines) - 1; i >= 0; i-- {
+		m := cr.machines[i]
+		name := "machine " + m.machineName
+		if err := m.Delete(ctx); err != nil {
+			rollbackErr.Left[name] = err
+			continue
+		}
+		if err := c.forgetKnownHosts(m); err != nil {
+			m.logger().Warnf("Can't forget the host keys of machine %s: %v", m.machineName, err)
+		}
+		rollbackErr.Removed = append(rollbackErr.Removed, name)
+	}
+	for _, path := range cr.files {
+		if !fileExists(path) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			rollbackErr.Left[path] = err
+			continue
+		}
+		rollbackErr.Removed = append(rollbackErr.Removed, path)
+	}
+
+	for _, name := range rollbackErr.Removed {
+		c.logger().Warnf("Rolled back: removed %s", name)
+	}
+	for _, name := range rollbackErr.left() {
+		c.logger().Errorf("Rollback: couldn't remove %s: %v", name, rollbackErr.Left[name])
+	}
+	return rollbackErr
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAtomic(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newFakeCluster(t)
+	failed := errors.New("exec failed")
+	onExec := fake.OnExec
+	fake.OnExec = func(container string, command []string, stdin []byte) ([]byte, error) {
+		if container == "fake-nodes-node1" {
+			return nil, failed
+		}
+		return onExec(container, command, stdin)
+	}
+
+	err := c.Create(ctx, CreateOptions{Atomic: true})
+	var rollbackErr *RollbackError
+	if assert.True(t, errors.As(err, &rollbackErr), "%v", err) {
+		assert.True(t, errors.Is(err, failed))
+		assert.Equal(t, []string{
+			"machine nodes-node1",
+			"machine nodes-node0",
+			c.KnownHostsPath(),
+			c.privateKeyPath(),
+			c.privateKeyPath() + ".pub",
+		}, rollbackErr.Removed)
+		assert.Empty(t, rollbackErr.Left)
+	}
+	assert.Empty(t, fake.Containers())
+	assert.False(t, fileExists(c.privateKeyPath()))
+}
+
+func TestCreateAtomicKeepsExisting(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newFakeCluster(t)
+	assert.NoError(t, c.Create(ctx, CreateOptions{}))
+	assert.NoError(t, fake.Remove(ctx, "fake-nodes-node1"))
+
+	failed := errors.New("exec failed")
+	onExec := fake.OnExec
+	fake.OnExec = func(container string, command []string, stdin []byte) ([]byte, error) {
+		if container == "fake-nodes-node1" {
+			return nil, failed
+		}
+		return onExec(container, command, stdin)
+	}
+	err := c.Create(ctx, CreateOptions{Atomic: true})
+	var rollbackErr *RollbackError
+	if assert.True(t, errors.As(err, &rollbackErr), "%v", err) {
+		assert.Equal(t, []string{"machine nodes-node1"}, rollbackErr.Removed)
+	}
+	if assert.Len(t, fake.Containers(), 1) {
+		assert.Equal(t, "fake-nodes-node0", fake.Containers()[0].Name)
+	}
+	assert.True(t, fileExists(c.privateKeyPath()))
+	assert.True(t, fileExists(c.KnownHostsPath()))
+}
+
+func TestCreateNotAtomic(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newFakeCluster(t)
+	failed := errors.New("exec failed")
+	fake.OnExec = func(container string, command []string, stdin []byte) ([]byte, error) {
+		return nil, failed
+	}
+
+	err := c.Create(ctx, CreateOptions{})
+	assert.Equal(t, failed, err)
+	assert.Len(t, fake.Containers(), 1)
+}
+
+func TestRollbackError(t *testing.T) {
+	err := &RollbackError{
+		Err:     errors.New("bad network"),
+		Removed: []string{"machine node1", "machine node0"},
+		Left:    map[string]error{"machine node2": errors.New("busy")},
+	}
+	assert.Equal(t, "bad network (rolled back: removed machine node1, machine node0; couldn't remove machine node2: busy)", err.Error())
+	assert.Equal(t, "bad network (rolled back: nothing to remove)", (&RollbackError{Err: errors.New("bad network")}).Error())
+}
@@ -18,13 +18,54 @@ package cluster
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
-	"github.com/brightzheng100/vind/pkg/docker"
 	"github.com/brightzheng100/vind/pkg/exec"
 	"github.com/brightzheng100/vind/pkg/utils"
 )
 
+// ExecOptions are the options of the commands run by Exec.
+type ExecOptions struct {
+	// User runs the command, root by default.
+	User string
+	// Env are the "KEY=value" environment variables of the command.
+	Env []string
+	// Stdin, Stdout and Stderr are the streams of the command, none by default.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Exec runs a command in the machine, which fails with ErrNotCreated or
+// ErrNotStarted if it's not running. A command exiting with a non-zero
+// status fails with an *exec.ExitError of os/exec.
+func (c *Cluster) Exec(ctx context.Context, machine *Machine, command []string, opts ExecOptions) error {
+	if len(command) < 1 {
+		return errors.New("exec: no command given")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !machine.IsCreated() {
+		return fmt.Errorf("%w: %s", ErrNotCreated, machine.machineName)
+	}
+	if !machine.IsStarted() {
+		return fmt.Errorf("%w: %s", ErrNotStarted, machine.machineName)
+	}
+
+	cmd := machine.runtime.Exec(ctx, machine.containerName, opts.User, command[0], command[1:]...)
+	cmd.SetEnv(opts.Env...)
+	cmd.SetStdin(opts.Stdin)
+	cmd.SetStdout(opts.Stdout)
+	cmd.SetStderr(opts.Stderr)
+	return cmd.Run()
+}
+
 // run runs a command in host. It will output the combined stdout/error on failure.
 func run(name string, args ...string) error {
 	cmd := exec.Command(name, args...)
@@ -38,28 +79,81 @@ func run(name string, args ...string) error {
 	return err
 }
 
-// Run a command in a container. It will output the combined stdout/error on failure.
-func containerRun(nameOrID string, name string, args ...string) error {
-	exe := docker.ContainerCmder(nameOrID)
-	cmd := exe.Command(name, args...)
+// run runs a command in the machine. It will output the combined stdout/error on failure.
+func (m *Machine) run(ctx context.Context, name string, args ...string) error {
+	cmd := m.runtime.Cmder(ctx, m.containerName).Command(name, args...)
 	output, err := exec.CombinedOutputLines(cmd)
 	if err != nil {
 		// log error output if there was any
 		for _, line := range output {
-			utils.Logger.WithField("machine", nameOrID).Error(line)
+			m.logger().Error(line)
 		}
 	}
 	return err
 }
 
-func containerRunShell(nameOrID string, script string) error {
-	return containerRun(nameOrID, "/bin/bash", "-c", script)
+// output runs a command in the machine and returns its output lines.
+// It will output the combined stdout/error on failure.
+func (m *Machine) output(ctx context.Context, name string, args ...string) ([]string, error) {
+	cmd := m.runtime.Cmder(ctx, m.containerName).Command(name, args...)
+	output, err := exec.CombinedOutputLines(cmd)
+	if err != nil {
+		// log error output if there was any
+		for _, line := range output {
+			m.logger().Error(line)
+		}
+		return nil, err
+	}
+	return output, nil
 }
 
-func copy(nameOrID string, content []byte, path string) error {
-	buf := bytes.Buffer{}
-	buf.WriteString(fmt.Sprintf("cat <<__EOF | tee -a %s\n", path))
-	buf.Write(content)
-	buf.WriteString("__EOF")
-	return containerRunShell(nameOrID, buf.String())
+func (m *Machine) runShell(ctx context.Context, script string) error {
+	return m.run(ctx, "/bin/bash", "-c", script)
+}
+
+// readFile returns the content of a file in the machine, or nothing if the
+// file doesn't exist.
+func (m *Machine) readFile(ctx context.Context, path string) ([]byte, error) {
+	lines, err := m.output(ctx, "/bin/sh", "-c", `[ ! -e "$1" ] || cat "$1"`, "sh", path)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		// output lines go through a tty
+		buf.WriteString(strings.TrimSuffix(line, "\r"))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFile writes a file in the machine, streaming its content through
+// stdin so no content needs escaping. The file is replaced atomically with
+// the given owner, as accepted by chown, and mode.
+func (m *Machine) writeFile(ctx context.Context, content []byte, path string, owner string, mode os.FileMode) error {
+	const script = `set -e
+f=$1
+t=$(mktemp "$f.XXXXXX")
+trap 'rm -f "$t"' EXIT
+cat > "$t"
+chown "$2" "$t"
+chmod "$3" "$t"
+mv -f "$t" "$f"
+`
+	return m.runInput(ctx, content, "/bin/sh", "-c", script, "sh", path, owner, fmt.Sprintf("%o", mode))
+}
+
+// runInput runs a command in the machine, streaming the input through its
+// stdin. It will output the combined stdout/error on failure.
+func (m *Machine) runInput(ctx context.Context, input []byte, name string, args ...string) error {
+	cmd := m.runtime.Cmder(ctx, m.containerName).Command(name, args...)
+	cmd.SetStdin(bytes.NewReader(input))
+	output, err := exec.CombinedOutputLines(cmd)
+	if err != nil {
+		// log error output if there was any
+		for _, line := range output {
+			m.logger().Error(line)
+		}
+	}
+	return err
 }
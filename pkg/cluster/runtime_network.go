@@ -18,6 +18,7 @@ package cluster
 
 import (
 	"net"
+	"sort"
 
 	"github.com/docker/docker/api/types/network"
 )
@@ -38,7 +39,7 @@ type RuntimeNetwork struct {
 	Gateway string `json:"gateway,omitempty"`
 }
 
-// NewRuntimeNetworks returns a slice of networks
+// NewRuntimeNetworks returns a slice of networks, sorted by name
 func NewRuntimeNetworks(networks map[string]*network.EndpointSettings) []*RuntimeNetwork {
 	rnList := make([]*RuntimeNetwork, 0, len(networks))
 	for key, value := range networks {
@@ -52,5 +53,6 @@ func NewRuntimeNetworks(networks map[string]*network.EndpointSettings) []*Runtim
 		}
 		rnList = append(rnList, rnNetwork)
 	}
+	sort.Slice(rnList, func(i, j int) bool { return rnList[i].Name < rnList[j].Name })
 	return rnList
 }
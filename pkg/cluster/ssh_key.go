@@ -0,0 +1,350 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/brightzheng100/vind/pkg/utils"
+	"github.com/pkg/errors"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// AuthorizedKey describes a public key allowed to log into a machine.
+type AuthorizedKey struct {
+	Type        string `json:"type"`
+	Fingerprint string `json:"fingerprint"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+// MachineKeys lists the public keys authorized on a machine.
+type MachineKeys struct {
+	MachineName string          `json:"machineName"`
+	User        string          `json:"user"`
+	Keys        []AuthorizedKey `json:"keys"`
+}
+
+// generateKey generates a SSH key pair of the given type. It returns the
+// private key in the OpenSSH format and the public key in the authorized_keys
+// format.
+func generateKey(keyType, comment string) (private []byte, public []byte, err error) {
+	var key crypto.Signer
+	switch keyType {
+	case "", config.KeyTypeED25519:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case config.KeyTypeRSA:
+		key, err = rsa.GenerateKey(rand.Reader, 4096)
+	case config.KeyTypeECDSA:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, nil, errors.Errorf("unsupported SSH key type '%s'", keyType)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "ssh key: generate")
+	}
+
+	block, err := gossh.MarshalPrivateKey(key, comment)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "ssh key: marshal private key")
+	}
+	signer, err := gossh.NewSignerFromSigner(key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "ssh key: marshal public key")
+	}
+	public = bytes.TrimSuffix(gossh.MarshalAuthorizedKey(signer.PublicKey()), []byte("\n"))
+	public = append(public, []byte(" "+comment+"\n")...)
+	return pem.EncodeToMemory(block), public, nil
+}
+
+// writeKey writes the private key to path and the public key next to it.
+func writeKey(path string, private, public []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrap(err, "ssh key: create key directory")
+	}
+	if err := os.WriteFile(path, private, 0600); err != nil {
+		return errors.Wrap(err, "ssh key: write private key")
+	}
+	if err := os.WriteFile(path+".pub", public, 0644); err != nil {
+		return errors.Wrap(err, "ssh key: write public key")
+	}
+	return nil
+}
+
+// parseAuthorizedKeys parses the content of an authorized_keys file, skipping
+// comments and the lines which aren't valid keys.
+func parseAuthorizedKeys(data []byte) []AuthorizedKey {
+	var keys []AuthorizedKey
+	for len(data) > 0 {
+		key, comment, _, rest, err := gossh.ParseAuthorizedKey(data)
+		if err != nil {
+			break
+		}
+		keys = append(keys, AuthorizedKey{
+			Type:        key.Type(),
+			Fingerprint: gossh.FingerprintSHA256(key),
+			Comment:     comment,
+		})
+		data = rest
+	}
+	return keys
+}
+
+// mergeAuthorizedKeys appends the keys to the content of an authorized_keys
+// file. Keys already authorized, whatever their comment, are skipped, as well
+// as empty lines.
+func mergeAuthorizedKeys(existing []byte, keys []byte) []byte {
+	var merged bytes.Buffer
+	seen := map[string]bool{}
+	for _, line := range strings.Split(string(existing)+"\n"+string(keys), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		id := line
+		if key, _, _, _, err := gossh.ParseAuthorizedKey([]byte(line)); err == nil {
+			id = string(key.Marshal())
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		merged.WriteString(line)
+		merged.WriteByte('\n')
+	}
+	return merged.Bytes()
+}
+
+// ClusterKey returns the path and the description of the cluster public key.
+func (c *Cluster) ClusterKey() (string, *AuthorizedKey, error) {
+	path := c.privateKeyPath()
+	if path == "" {
+		return "", nil, errors.New("no SSH key provided")
+	}
+	data, err := os.ReadFile(path + ".pub")
+	if err != nil {
+		return path, nil, errors.Wrap(err, "ssh key: read public key")
+	}
+	keys := parseAuthorizedKeys(data)
+	if len(keys) < 1 {
+		return path, nil, errors.Errorf("ssh key: no public key found in %s.pub", path)
+	}
+	return path, &keys[0], nil
+}
+
+// AuthorizedKeys lists the keys authorized on all or specific running machines.
+func (c *Cluster) AuthorizedKeys(ctx context.Context, machineNames []string) ([]MachineKeys, error) {
+	machines, err := c.Show(ctx, machineNames)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []MachineKeys
+	for _, m := range machines {
+		if !m.IsStarted() {
+			m.logger().Warnf("machine not started: %s", m.machineName)
+			continue
+		}
+		for _, user := range m.Users() {
+			lines, err := m.output(ctx, "cat", m.authorizedKeysPath(user))
+			if err != nil {
+				return list, errors.Wrapf(err, "can't read authorized keys of %s on %s", user, m.machineName)
+			}
+			list = append(list, MachineKeys{
+				MachineName: m.machineName,
+				User:        user,
+				Keys:        parseAuthorizedKeys([]byte(strings.Join(lines, "\n"))),
+			})
+		}
+	}
+	return list, nil
+}
+
+// RotateSSHKey replaces the cluster SSH key pair with a newly generated one and
+// installs it into the running machines, replacing their authorized keys.
+// The stopped machines get it when they start. The previous key pair is kept
+// next to the new one with a ".old" suffix, and a rotation fails while some
+// machines only authorize it.
+func (c *Cluster) RotateSSHKey(ctx context.Context) error {
+	path := c.privateKeyPath()
+	if path == "" {
+		return errors.New("no SSH key provided")
+	}
+	if err := c.runtime.IsRunning(ctx); err != nil {
+		return err
+	}
+	state, err := c.State()
+	if err != nil {
+		return err
+	}
+	if len(state.StaleKeys) > 0 {
+		return errors.Errorf("ssh key: machines %s only authorize %s.old, start them before rotating again",
+			strings.Join(state.StaleKeys, ", "), path)
+	}
+
+	c.logger().Infof("Rotating SSH key: %s ...", path)
+	private, public, err := generateKey(c.config.Cluster.KeyType, f("%s@vind.mail", c.Name()))
+	if err != nil {
+		return err
+	}
+	// the authorized keys of the machines refer to the previous key until
+	// they're replaced, which a dry run only records
+	previous, _ := os.ReadFile(path + ".pub")
+	if c.dryRun {
+		c.logger().Infof("Dry run: backing up %s with a .old suffix, and writing the new key pair", path)
+	} else if err := replaceKey(path, private, public); err != nil {
+		return err
+	}
+
+	var stopped []string
+	err = c.forEachMachine(withContext(ctx, func(m *Machine) error {
+		if !m.IsCreated() {
+			return nil
+		}
+		if !m.IsStarted() {
+			m.logger().Infof("Machine %s is not running, its authorized keys will be replaced when it starts", m.machineName)
+			stopped = append(stopped, m.machineName)
+			return nil
+		}
+		pk, err := c.publicKey(m.spec, m.User())
+		if err != nil {
+			return errors.Wrap(err, "can't retrieve public key")
+		}
+		if c.dryRun && bytes.Equal(pk, previous) {
+			pk = public
+		}
+		m.logger().Infof("Replacing authorized keys of machine %s ...", m.machineName)
+		return m.authorizeKeys(ctx, m.User(), pk, true)
+	}))
+	if len(stopped) > 0 {
+		if stateErr := c.updateState(func(s *State) { s.StaleKeys = stopped }); err == nil {
+			err = stateErr
+		}
+	}
+	return err
+}
+
+// replaceKey backs up the key pair at path with a ".old" suffix, and writes
+// the new one. The backup is restored if the new key pair can't be written.
+func replaceKey(path string, private, public []byte) error {
+	var backedUp []string
+	for _, p := range []string{path, path + ".pub"} {
+		if !fileExists(p) {
+			continue
+		}
+		if err := os.Rename(p, p+".old"); err != nil {
+			restoreKey(backedUp)
+			return errors.Wrap(err, "ssh key: back up previous key")
+		}
+		backedUp = append(backedUp, p)
+	}
+	if err := writeKey(path, private, public); err != nil {
+		restoreKey(backedUp)
+		return err
+	}
+	return nil
+}
+
+// restoreKey moves the backed up key files back.
+func restoreKey(paths []string) {
+	for _, p := range paths {
+		if err := os.Rename(p+".old", p); err != nil {
+			utils.Logger.Warnf("Can't restore %s: %v", p, err)
+		}
+	}
+}
+
+// authorizeStaleKeys replaces the authorized keys of a machine which was
+// stopped while the cluster SSH key was rotated.
+func (c *Cluster) authorizeStaleKeys(ctx context.Context, m *Machine) error {
+	state, err := c.State()
+	if err != nil || !slices.Contains(state.StaleKeys, m.machineName) {
+		return err
+	}
+	pk, err := c.publicKey(m.spec, m.User())
+	if err != nil {
+		return errors.Wrap(err, "can't retrieve public key")
+	}
+	m.logger().Infof("Replacing authorized keys of machine %s, the SSH key was rotated ...", m.machineName)
+	if err := m.authorizeKeys(ctx, m.User(), pk, true); err != nil {
+		return err
+	}
+	return c.updateState(func(s *State) {
+		s.StaleKeys = slices.DeleteFunc(s.StaleKeys, func(name string) bool { return name == m.machineName })
+	})
+}
+
+// forgetStaleKeys removes the recorded stale keys, once the machines are
+// deleted.
+func (c *Cluster) forgetStaleKeys() error {
+	state, err := c.State()
+	if err != nil || len(state.StaleKeys) == 0 {
+		return err
+	}
+	return c.updateState(func(s *State) { s.StaleKeys = nil })
+}
+
+// SyncKeys installs the configured public keys into all or specific running
+// machines, repairing their authorized_keys files. The keys already authorized
+// are kept, unless prune is set.
+func (c *Cluster) SyncKeys(ctx context.Context, machineNames []string, prune bool) error {
+	if err := c.runtime.IsRunning(ctx); err != nil {
+		return err
+	}
+
+	syncMachineFun := withContext(ctx, func(m *Machine) error {
+		if !m.IsCreated() || !m.IsStarted() {
+			m.logger().Infof("Machine %s is not running, skipping...", m.machineName)
+			return nil
+		}
+		for _, user := range m.Users() {
+			pk, err := c.publicKey(m.spec, user)
+			if err != nil {
+				return errors.Wrap(err, "can't retrieve public key")
+			}
+			m.logger().Infof("Syncing authorized keys of user %s on machine %s ...", user, m.machineName)
+			if err := m.runShell(ctx, f(INIT_SCRIPT, user)); err != nil {
+				return err
+			}
+			if err := m.authorizeKeys(ctx, user, pk, prune); err != nil {
+				return err
+			}
+		}
+		if c.caEnabled() {
+			if err := c.configureCA(ctx, m); err != nil {
+				return err
+			}
+		}
+		return c.refreshKnownHosts(ctx, m)
+	})
+
+	if len(machineNames) < 1 {
+		return c.forEachMachine(syncMachineFun)
+	}
+	return c.forSpecificMachines(syncMachineFun, machineNames)
+}
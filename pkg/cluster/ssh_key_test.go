@@ -0,0 +1,108 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/stretchr/testify/assert"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestGenerateKey(t *testing.T) {
+	tests := []struct {
+		keyType  string
+		expected string
+	}{
+		{"", gossh.KeyAlgoED25519},
+		{config.KeyTypeED25519, gossh.KeyAlgoED25519},
+		{config.KeyTypeRSA, gossh.KeyAlgoRSA},
+		{config.KeyTypeECDSA, gossh.KeyAlgoECDSA256},
+	}
+
+	for _, utest := range tests {
+		t.Run(utest.expected, func(t *testing.T) {
+			private, public, err := generateKey(utest.keyType, "cluster@vind.mail")
+			assert.NoError(t, err)
+
+			signer, err := gossh.ParsePrivateKey(private)
+			assert.NoError(t, err)
+			assert.Equal(t, utest.expected, signer.PublicKey().Type())
+
+			keys := parseAuthorizedKeys(public)
+			assert.Equal(t, 1, len(keys))
+			assert.Equal(t, utest.expected, keys[0].Type)
+			assert.Equal(t, "cluster@vind.mail", keys[0].Comment)
+			assert.Equal(t, gossh.FingerprintSHA256(signer.PublicKey()), keys[0].Fingerprint)
+		})
+	}
+
+	_, _, err := generateKey("dsa", "cluster@vind.mail")
+	assert.Error(t, err)
+}
+
+func TestParseAuthorizedKeys(t *testing.T) {
+	_, key1, _ := generateKey(config.KeyTypeED25519, "one")
+	_, key2, _ := generateKey(config.KeyTypeED25519, "two")
+	data := strings.Join([]string{
+		"# a comment",
+		strings.TrimSpace(string(key1)) + "\r",
+		"not a key",
+		string(key2),
+	}, "\n")
+
+	keys := parseAuthorizedKeys([]byte(data))
+	assert.Equal(t, 2, len(keys))
+	assert.Equal(t, "one", keys[0].Comment)
+	assert.Equal(t, "two", keys[1].Comment)
+}
+
+func TestPrivateKeyPath(t *testing.T) {
+	t.Setenv(HomeEnv, "/tmp/vind-home")
+	wd, _ := os.Getwd()
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	c := &Cluster{config: config.Config{Cluster: config.Cluster{Name: "mycluster"}}}
+	assert.Equal(t, "", c.privateKeyPath())
+
+	c.config.Cluster.PrivateKey = "cluster-key"
+	assert.Equal(t, "/tmp/vind-home/clusters/mycluster/cluster-key", c.privateKeyPath())
+
+	c.config.Cluster.PrivateKey = "./cluster-key"
+	assert.Equal(t, "./cluster-key", c.privateKeyPath())
+
+	c.config.Cluster.PrivateKey = "/keys/cluster-key"
+	assert.Equal(t, "/keys/cluster-key", c.privateKeyPath())
+}
+
+func TestMergeAuthorizedKeys(t *testing.T) {
+	_, alice, _ := generateKey(config.KeyTypeED25519, "alice@example.com")
+	_, bob, _ := generateKey(config.KeyTypeED25519, "bob@example.com")
+	aliceRenamed := strings.Replace(string(alice), "alice@example.com", "alice@laptop", 1)
+
+	existing := "# managed by vind\n" + string(alice) + "\n\n" + string(alice)
+	merged := mergeAuthorizedKeys([]byte(existing), []byte(aliceRenamed+string(bob)))
+	assert.Equal(t, "# managed by vind\n"+string(alice)+string(bob), string(merged))
+
+	// merging again changes nothing
+	assert.Equal(t, string(merged), string(mergeAuthorizedKeys(merged, bob)))
+
+	assert.Equal(t, string(bob), string(mergeAuthorizedKeys(nil, bob)))
+}
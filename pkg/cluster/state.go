@@ -0,0 +1,187 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/brightzheng100/vind/pkg/proxy"
+	"github.com/pkg/errors"
+)
+
+// State is what vind tracks about a cluster at runtime, besides the machines
+// themselves.
+type State struct {
+	PortForwards  []PortForward  `json:"portForwards,omitempty"`
+	NetworkFaults []NetworkFault `json:"networkFaults,omitempty"`
+	Partitions    []Partition    `json:"partitions,omitempty"`
+	// StaleKeys are the machines which were stopped while the cluster SSH key
+	// was rotated. Their authorized keys are replaced when they start.
+	StaleKeys []string `json:"staleKeys,omitempty"`
+}
+
+// PortForward is a running "vind port-forward" process.
+type PortForward struct {
+	MachineName string          `json:"machineName"`
+	PID         int             `json:"pid"`
+	Via         string          `json:"via"`
+	Forwards    []proxy.Forward `json:"forwards"`
+	Started     time.Time       `json:"started"`
+	// ProcessStart is the start time of the process, as returned by
+	// exec.ProcessStart, telling it apart from a later process reusing the
+	// pid. It's zero where it's not supported.
+	ProcessStart uint64 `json:"processStart,omitempty"`
+	// Log is the log file of the process running in the background.
+	Log string `json:"log,omitempty"`
+}
+
+// alive returns whether the process of the port forward is still running,
+// and not replaced by another process with the same pid.
+func (pf *PortForward) alive() bool {
+	if !exec.ProcessAlive(pf.PID) {
+		return false
+	}
+	if pf.ProcessStart == 0 {
+		return true
+	}
+	start, err := exec.ProcessStart(pf.PID)
+	return err == nil && start == pf.ProcessStart
+}
+
+// NetworkFault is a "vind netem" impairment of the traffic sent by a machine,
+// to the given machines only if any.
+type NetworkFault struct {
+	MachineName string    `json:"machineName"`
+	Netem       Netem     `json:"netem"`
+	To          []string  `json:"to,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+// Partition is a "vind partition", dropping the traffic between machines of
+// different groups.
+type Partition struct {
+	Groups  [][]string `json:"groups"`
+	Created time.Time  `json:"created"`
+}
+
+// String formats the partition like "node0,node1 | node2".
+func (p Partition) String() string {
+	groups := make([]string, len(p.Groups))
+	for i, group := range p.Groups {
+		groups[i] = strings.Join(group, ",")
+	}
+	return strings.Join(groups, " | ")
+}
+
+// statePath returns the path of the state file of the cluster.
+func (c *Cluster) statePath() string {
+	return filepath.Join(c.Dir(), "state.json")
+}
+
+// State returns the runtime state of the cluster, without the entries of
+// the processes which are gone.
+func (c *Cluster) State() (*State, error) {
+	state := &State{}
+	data, err := os.ReadFile(c.statePath())
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "state: read")
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrapf(err, "state: parse %s", c.statePath())
+	}
+	state.prune()
+	return state, nil
+}
+
+// stateLockWait is how long updateState waits for another process to be
+// done with the state.
+const stateLockWait = 10 * time.Second
+
+// stateLockPath returns the path of the lock file of the state updates. It's
+// not the cluster lock, which the process updating the state may hold.
+func (c *Cluster) stateLockPath() string {
+	return c.statePath() + ".lock"
+}
+
+// updateState applies the update to the state of the cluster and saves it.
+// The updates of concurrent processes are serialized by a lock, and the state
+// is replaced at once, so that it's never read partially written.
+func (c *Cluster) updateState(update func(*State)) error {
+	if c.dryRun {
+		c.logger().Infof("Dry run: updating %s", c.statePath())
+		return nil
+	}
+	if err := os.MkdirAll(c.Dir(), 0700); err != nil {
+		return errors.Wrap(err, "state: create directory")
+	}
+	lock, err := lockFile(context.Background(), c.stateLockPath(), stateLockWait)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		return errors.Errorf("state: %s is locked by another process, waited %s", c.statePath(), stateLockWait)
+	}
+	defer func() {
+		unlockFile(lock)
+		lock.Close()
+	}()
+
+	state, err := c.State()
+	if err != nil {
+		return err
+	}
+	update(state)
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Dir(), "state-*.json")
+	if err != nil {
+		return errors.Wrap(err, "state: write")
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "state: write")
+	}
+	if err := os.Rename(tmp.Name(), c.statePath()); err != nil {
+		return errors.Wrap(err, "state: write")
+	}
+	return nil
+}
+
+// prune drops the entries of the processes which are gone.
+func (s *State) prune() {
+	forwards := s.PortForwards[:0]
+	for _, pf := range s.PortForwards {
+		if pf.alive() {
+			forwards = append(forwards, pf)
+		}
+	}
+	s.PortForwards = forwards
+}
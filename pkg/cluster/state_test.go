@@ -0,0 +1,92 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/brightzheng100/vind/pkg/proxy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState(t *testing.T) {
+	t.Setenv(HomeEnv, t.TempDir())
+	c := &Cluster{config: config.Config{Cluster: config.Cluster{Name: "mycluster"}}}
+
+	state, err := c.State()
+	assert.NoError(t, err)
+	assert.Empty(t, state.PortForwards)
+
+	forwards := []proxy.Forward{{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}}
+	err = c.updateState(func(s *State) {
+		s.PortForwards = append(s.PortForwards,
+			PortForward{MachineName: "test-node0", PID: os.Getpid(), Via: ForwardViaIP, Forwards: forwards},
+			// a process which is gone
+			PortForward{MachineName: "test-node1", PID: 1 << 30, Via: ForwardViaExec, Forwards: forwards},
+		)
+		if start, err := exec.ProcessStart(os.Getpid()); err == nil {
+			s.PortForwards[0].ProcessStart = start
+			// a process which is gone, whose pid is reused
+			s.PortForwards = append(s.PortForwards,
+				PortForward{MachineName: "test-node2", PID: os.Getpid(), ProcessStart: start + 1, Forwards: forwards},
+			)
+		}
+	})
+	assert.NoError(t, err)
+
+	state, err = c.State()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(state.PortForwards))
+	assert.Equal(t, "test-node0", state.PortForwards[0].MachineName)
+	assert.Equal(t, forwards, state.PortForwards[0].Forwards)
+
+	m := newMachine(&c.config.Cluster, &config.MachineSet{Name: "test"}, &config.Machine{Name: "node%d"}, 0)
+	assert.Equal(t, 1, len(c.portForwardsOf([]*Machine{m})))
+	m = newMachine(&c.config.Cluster, &config.MachineSet{Name: "test"}, &config.Machine{Name: "node%d"}, 1)
+	assert.Empty(t, c.portForwardsOf([]*Machine{m}))
+}
+
+func TestUpdateStateConcurrently(t *testing.T) {
+	t.Setenv(HomeEnv, t.TempDir())
+	c := &Cluster{config: config.Config{Cluster: config.Cluster{Name: "mycluster"}}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.updateState(func(s *State) {
+				s.Partitions = append(s.Partitions, Partition{Groups: [][]string{{f("node%d", i)}}})
+			}))
+		}()
+	}
+	wg.Wait()
+
+	state, err := c.State()
+	assert.NoError(t, err)
+	assert.Len(t, state.Partitions, 20)
+	entries, err := os.ReadDir(c.Dir())
+	assert.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"state.json", "state.json.lock"}, names)
+}
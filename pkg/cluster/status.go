@@ -17,14 +17,17 @@ limitations under the License.
 package cluster
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
+	"slices"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/brightzheng100/vind/pkg/config"
-	"github.com/mitchellh/go-homedir"
 	"gopkg.in/yaml.v2"
 )
 
@@ -50,10 +53,33 @@ type MachineStatus struct {
 	RuntimeNetworks []*RuntimeNetwork `json:"runtimeNetworks,omitempty"`
 }
 
+// Status returns the status of all the machines of the cluster, or of the
+// given ones, created or not.
+func (c *Cluster) Status(ctx context.Context, machineNames []string) ([]*MachineStatus, error) {
+	if err := c.runtime.IsRunning(ctx); err != nil {
+		return nil, err
+	}
+	var statuses []*MachineStatus
+	status := withContext(ctx, func(m *Machine) error {
+		statuses = append(statuses, m.Status())
+		return nil
+	})
+	var err error
+	if len(machineNames) < 1 {
+		err = c.forEachMachine(status)
+	} else {
+		err = c.forSpecificMachines(status, machineNames)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
 // Formatter formats a slice of machines and outputs the result
 // in a given format.
 type Formatter interface {
-	Format(io.Writer, *cluster, []*Machine) error
+	Format(io.Writer, *Cluster, []*Machine) error
 }
 
 // JSONFormatter formats a slice of machines into a JSON and
@@ -69,25 +95,73 @@ type TableFormatter struct{}
 type AnsibleFormatter struct{}
 
 // SSHConfigFormatter formats a slice of machines into ssh_config and
-// outputs it to stdout
-type SSHConfigFormatter struct{}
+// outputs it to stdout. Machines without a published SSH port, or all of
+// them with ProxyCommand, are reached by tunnelling SSH over docker exec.
+type SSHConfigFormatter struct {
+	ProxyCommand bool
+}
 
 type port struct {
-	Guest int `json:"guest"`
-	Host  int `json:"host"`
+	Guest    int    `json:"guest"`
+	Host     int    `json:"host"`
+	Protocol string `json:"protocol,omitempty"`
+	Address  string `json:"address,omitempty"`
+}
+
+// String formats the port like "[address:]host->guest[/udp]". Wildcard
+// addresses and the default "tcp" protocol are omitted.
+func (p port) String() string {
+	s := fmt.Sprintf("%d->%d", p.Host, p.Guest)
+	if p.Address != "" && p.Address != "0.0.0.0" && p.Address != "::" {
+		s = net.JoinHostPort(p.Address, s)
+	}
+	if p.Protocol != "" && p.Protocol != "tcp" {
+		s += "/" + p.Protocol
+	}
+	return s
+}
+
+// formatPorts joins the ports for display, skipping the duplicates an IPv4
+// and IPv6 binding of the same port would otherwise produce.
+func formatPorts(ports []port) string {
+	var formatted []string
+	for _, p := range ports {
+		s := p.String()
+		if !slices.Contains(formatted, s) {
+			formatted = append(formatted, s)
+		}
+	}
+	return strings.Join(formatted, ",")
+}
+
+// sshAddress returns the host and the port to reach the TCP container port 22
+// from the host, as sshEndpoint does, or a zero port if it's not published.
+func sshAddress(ports []port) (string, int) {
+	var bindings []PortBinding
+	for _, p := range ports {
+		if p.Guest == 22 && p.Host != 0 && (p.Protocol == "" || p.Protocol == "tcp") {
+			bindings = append(bindings, PortBinding{HostIP: p.Address, HostPort: p.Host})
+		}
+	}
+	if len(bindings) == 0 {
+		return "localhost", 0
+	}
+	return sshEndpoint(bindings)
 }
 
 // Format will output to stdout in JSON format.
-func (JSONFormatter) Format(w io.Writer, _ *cluster, machines []*Machine) error {
+func (JSONFormatter) Format(w io.Writer, c *Cluster, machines []*Machine) error {
 	var statuses []MachineStatus
 	for _, m := range machines {
 		statuses = append(statuses, *m.Status())
 	}
 
 	m := struct {
-		Machines []MachineStatus `json:"machines"`
+		Machines     []MachineStatus `json:"machines"`
+		PortForwards []PortForward   `json:"portForwards,omitempty"`
 	}{
-		Machines: statuses,
+		Machines:     statuses,
+		PortForwards: c.portForwardsOf(machines),
 	}
 	ms, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
@@ -123,7 +197,7 @@ func (wr writer) writeColumns(w io.Writer, cols []string) {
 }
 
 // Format will output to stdout in table format.
-func (TableFormatter) Format(w io.Writer, _ *cluster, machines []*Machine) error {
+func (TableFormatter) Format(w io.Writer, c *Cluster, machines []*Machine) error {
 	const padding = 3
 	wr := new(writer)
 	var statuses []MachineStatus
@@ -138,37 +212,75 @@ func (TableFormatter) Format(w io.Writer, _ *cluster, machines []*Machine) error
 		return wr.err
 	}
 	for _, s := range statuses {
-		var ports []string
-		for _, port := range s.Ports {
-			p := fmt.Sprintf("%d->%d", port.Host, port.Guest)
-			ports = append(ports, p)
-		}
+		ports := s.Ports
 		if len(ports) < 1 {
 			for _, p := range s.Spec.PortMappings {
-				port := fmt.Sprintf("%d->%d", p.HostPort, p.ContainerPort)
-				ports = append(ports, port)
+				ports = append(ports, port{Host: int(p.HostPort), Guest: int(p.ContainerPort), Protocol: p.Protocol})
 			}
 		}
-		ps := strings.Join(ports, ",")
+		ps := formatPorts(ports)
 		wr.writeColumns(table, []string{s.Container, s.MachineName, ps, s.IP, s.Image, s.Command, s.State})
 	}
 
+	if wr.err != nil {
+		return wr.err
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	forwards := c.portForwardsOf(machines)
+	if len(forwards) < 1 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+	table = tabwriter.NewWriter(w, 0, 0, padding, ' ', 0)
+	wr.writeColumns(table, []string{"PORT FORWARDS", "MACHINE NAME", "PORTS", "VIA", "PID", "STARTED"})
+	for _, pf := range forwards {
+		ports := make([]string, len(pf.Forwards))
+		for i, f := range pf.Forwards {
+			ports[i] = f.String()
+		}
+		wr.writeColumns(table, []string{"", pf.MachineName, strings.Join(ports, ","), pf.Via, fmt.Sprint(pf.PID), pf.Started.Format(time.RFC3339)})
+	}
 	if wr.err != nil {
 		return wr.err
 	}
 	return table.Flush()
 }
 
-func (AnsibleFormatter) Format(w io.Writer, c *cluster, machines []*Machine) error {
+// portForwardsOf returns the running port forwards of the machines. Failing
+// to read them isn't worth failing the display of the machines.
+func (c *Cluster) portForwardsOf(machines []*Machine) []PortForward {
+	state, err := c.State()
+	if err != nil {
+		c.logger().Warnf("Can't read the port forwards: %v", err)
+		return nil
+	}
+	var forwards []PortForward
+	for _, pf := range state.PortForwards {
+		for _, m := range machines {
+			if m.machineName == pf.MachineName {
+				forwards = append(forwards, pf)
+				break
+			}
+		}
+	}
+	return forwards
+}
+
+func (AnsibleFormatter) Format(w io.Writer, c *Cluster, machines []*Machine) error {
 	var statuses []MachineStatus
 	for _, m := range machines {
 		statuses = append(statuses, *m.Status())
 	}
-	path, _ := homedir.Expand(c.config.Cluster.PrivateKey)
+	path := c.privateKeyPath()
 
 	args := []string{
-		"-o", "UserKnownHostsFile=/dev/null",
-		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=" + c.KnownHostsPath(),
+		"-o", "StrictHostKeyChecking=yes",
 	}
 
 	m := map[string]interface{}{}
@@ -178,12 +290,9 @@ func (AnsibleFormatter) Format(w io.Writer, c *cluster, machines []*Machine) err
 		if s.Spec.User == "" {
 			user = defaultUser
 		}
-		port := 0
-		if len(s.Ports) > 0 {
-			port = s.Ports[0].Host
-		}
+		host, port := sshAddress(s.Ports)
 		hosts[s.MachineName] = map[string]interface{}{
-			"ansible_host":                 "localhost",
+			"ansible_host":                 host,
 			"ansible_port":                 port,
 			"ansible_user":                 user,
 			"ansible_connection":           "ssh",
@@ -202,16 +311,23 @@ func (AnsibleFormatter) Format(w io.Writer, c *cluster, machines []*Machine) err
 	return err
 }
 
-func (SSHConfigFormatter) Format(w io.Writer, c *cluster, machines []*Machine) error {
+// sshConfigOptions are the options of the hosts written by the
+// SSHConfigFormatter, in their order.
+var sshConfigOptions = []string{
+	"Hostname", "Port", "ProxyCommand", "HostKeyAlias",
+	"User", "IdentityFile", "UserKnownHostsFile", "StrictHostKeyChecking",
+}
+
+func (formatter SSHConfigFormatter) Format(w io.Writer, c *Cluster, machines []*Machine) error {
 	var statuses []MachineStatus
 	for _, m := range machines {
 		statuses = append(statuses, *m.Status())
 	}
-	path, _ := homedir.Expand(c.config.Cluster.PrivateKey)
+	path := c.privateKeyPath()
 
 	args := map[string]interface{}{
-		"UserKnownHostsFile":    "/dev/null",
-		"StrictHostKeyChecking": "no",
+		"UserKnownHostsFile":    c.KnownHostsPath(),
+		"StrictHostKeyChecking": "yes",
 	}
 
 	l := []string{}
@@ -220,23 +336,26 @@ func (SSHConfigFormatter) Format(w io.Writer, c *cluster, machines []*Machine) e
 		if s.Spec.User == "" {
 			user = defaultUser
 		}
-		port := 0
-		if len(s.Ports) > 0 {
-			port = s.Ports[0].Host
-		}
 		opts := map[string]interface{}{
-			"Hostname":     "localhost",
-			"Port":         port,
 			"User":         user,
 			"IdentityFile": path,
 		}
+		if host, port := sshAddress(s.Ports); port != 0 && !formatter.ProxyCommand {
+			opts["Hostname"] = host
+			opts["Port"] = port
+		} else {
+			opts["ProxyCommand"] = proxyCommand(s.Container)
+			opts["HostKeyAlias"] = s.Container
+		}
 		for arg, val := range args {
 			opts[arg] = val
 		}
 
 		h := fmt.Sprintf("Host %s\n", s.MachineName)
-		for opt, val := range opts {
-			h += fmt.Sprintf("    %s %v\n", opt, val)
+		for _, opt := range sshConfigOptions {
+			if val, ok := opts[opt]; ok {
+				h += fmt.Sprintf("    %s %v\n", opt, val)
+			}
 		}
 		l = append(l, h)
 	}
@@ -248,3 +367,9 @@ func (SSHConfigFormatter) Format(w io.Writer, c *cluster, machines []*Machine) e
 	}
 	return nil
 }
+
+// proxyCommand returns the ssh ProxyCommand tunnelling SSH to the container
+// over docker exec.
+func proxyCommand(container string) string {
+	return fmt.Sprintf("docker exec -i %s nc localhost 22", container)
+}
@@ -0,0 +1,51 @@
+/*
+Copyright © 2019-2023 footloose developers
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatPorts(t *testing.T) {
+	ports := []port{
+		{Host: 32768, Guest: 22, Protocol: "tcp", Address: "0.0.0.0"},
+		{Host: 32768, Guest: 22, Protocol: "tcp", Address: "::"},
+		{Host: 5353, Guest: 53, Protocol: "udp", Address: "127.0.0.1"},
+		{Host: 8080, Guest: 80, Protocol: "tcp", Address: "::1"},
+	}
+	assert.Equal(t, "32768->22,127.0.0.1:5353->53/udp,[::1]:8080->80", formatPorts(ports))
+}
+
+func TestSSHAddress(t *testing.T) {
+	ports := []port{
+		{Host: 5353, Guest: 22, Protocol: "udp"},
+		{Host: 8080, Guest: 80, Protocol: "tcp"},
+		{Host: 2222, Guest: 22, Protocol: "tcp", Address: "0.0.0.0"},
+	}
+	host, hostPort := sshAddress(ports)
+	assert.Equal(t, "localhost", host)
+	assert.Equal(t, 2222, hostPort)
+
+	host, hostPort = sshAddress([]port{{Host: 2223, Guest: 22, Protocol: "tcp", Address: "127.0.0.1"}})
+	assert.Equal(t, "127.0.0.1", host)
+	assert.Equal(t, 2223, hostPort)
+
+	_, hostPort = sshAddress(nil)
+	assert.Equal(t, 0, hostPort)
+}
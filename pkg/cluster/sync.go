@@ -0,0 +1,304 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/utils"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+// defaultSyncBatch is how long changes are collected before being pushed.
+const defaultSyncBatch = 200 * time.Millisecond
+
+// SyncOptions are the options of Sync.
+type SyncOptions struct {
+	CopyOptions
+	// Ignore lists the patterns, as in path.Match, of the files not synced.
+	// They're matched against the paths relative to the synced directory, and
+	// any of their parent directories, and against the base names.
+	Ignore []string
+	// Delete removes from the machines the files removed from the host.
+	Delete bool
+	// Batch is how long changes are collected before being pushed.
+	Batch time.Duration
+	// Once pushes the directory once, without watching it.
+	Once bool
+}
+
+// Sync pushes the content of the host directory srcDir into the directory
+// destDir of the machines, then watches srcDir and pushes its changes until
+// the context is done, unless opts.Once is set. It fails once srcDir itself is
+// removed or renamed.
+func (c *Cluster) Sync(ctx context.Context, srcDir string, to []*Machine, destDir string, opts SyncOptions) error {
+	info, err := os.Stat(srcDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.Errorf("%s is not a directory", srcDir)
+	}
+	if opts.Batch <= 0 {
+		opts.Batch = defaultSyncBatch
+	}
+
+	var watcher *fsnotify.Watcher
+	if !opts.Once {
+		// watch before the first push, so that no change is missed in between
+		if watcher, err = fsnotify.NewWatcher(); err != nil {
+			return errors.Wrap(err, "can't watch files")
+		}
+		defer watcher.Close()
+		if err := watchTree(watcher, srcDir, "", opts.Ignore); err != nil {
+			return err
+		}
+	}
+
+	err = forMachinesInParallel(to, func(m *Machine) error {
+		return m.run(ctx, "mkdir", "-p", destDir)
+	})
+	if err != nil {
+		return err
+	}
+	c.logger().Infof("Syncing %s to %s on %d machine(s) ...", srcDir, destDir, len(to))
+	if err := pushChanges(ctx, srcDir, []string{""}, to, destDir, opts); err != nil {
+		return err
+	}
+	if opts.Once {
+		return nil
+	}
+
+	c.logger().Infof("Watching %s for changes, press Ctrl+C to stop", srcDir)
+	pending := map[string]bool{}
+	timer := time.NewTimer(opts.Batch)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.Errors:
+			c.logger().Warnf("Watching files: %v", err)
+		case event := <-watcher.Events:
+			rel, err := filepath.Rel(srcDir, event.Name)
+			if err != nil {
+				continue
+			}
+			rel = filepath.ToSlash(rel)
+			if rel == "." {
+				// the source itself, whose removal must not be pushed as
+				// the removal of destDir
+				if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+					return errors.Errorf("source directory %s is gone, stopped syncing", srcDir)
+				}
+				continue
+			}
+			if ignored(rel, opts.Ignore) {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+					if err := watchTree(watcher, srcDir, rel, opts.Ignore); err != nil {
+						c.logger().Warnf("Watching %s: %v", event.Name, err)
+					}
+				}
+			}
+			c.logger().Debugf("Changed: %s", event)
+			pending[rel] = true
+			timer.Reset(opts.Batch)
+		case <-timer.C:
+			paths := make([]string, 0, len(pending))
+			for rel := range pending {
+				paths = append(paths, rel)
+			}
+			pending = map[string]bool{}
+			if err := pushChanges(ctx, srcDir, paths, to, destDir, opts); err != nil {
+				c.logger().Errorf("Syncing changes: %v", err)
+			}
+		}
+	}
+}
+
+// watchTree watches the directory rel of srcDir and all its directories but
+// the ignored ones.
+func watchTree(watcher *fsnotify.Watcher, srcDir string, rel string, ignore []string) error {
+	return filepath.WalkDir(filepath.Join(srcDir, filepath.FromSlash(rel)), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		r, _ := filepath.Rel(srcDir, p)
+		if r != "." && ignored(filepath.ToSlash(r), ignore) {
+			return filepath.SkipDir
+		}
+		return errors.Wrapf(watcher.Add(p), "can't watch %s", p)
+	})
+}
+
+// pushChanges copies the paths, relative to srcDir, which still exist into
+// the machines, and removes the others from them if opts.Delete is set. The
+// empty path stands for the whole srcDir, which is never removed from them.
+func pushChanges(ctx context.Context, srcDir string, paths []string, to []*Machine, destDir string, opts SyncOptions) error {
+	sort.Strings(paths)
+	var changed, deleted []string
+	for _, rel := range paths {
+		if _, err := os.Lstat(filepath.Join(srcDir, filepath.FromSlash(rel))); os.IsNotExist(err) {
+			if rel == "" || rel == "." {
+				return errors.Errorf("source directory %s is gone", srcDir)
+			}
+			deleted = append(deleted, rel)
+		} else {
+			changed = append(changed, rel)
+		}
+	}
+
+	var archive bytes.Buffer
+	if len(changed) > 0 {
+		if err := writeArchive(&archive, srcDir, changed, opts.Ignore); err != nil {
+			return err
+		}
+	}
+	if len(deleted) > 0 && !opts.Delete {
+		utils.Logger.Debugf("Keeping deleted files in machines: %v", deleted)
+		deleted = nil
+	}
+	if len(changed) == 0 && len(deleted) == 0 {
+		return nil
+	}
+
+	utils.Logger.Infof("Syncing %d changed and %d deleted path(s)", len(changed), len(deleted))
+	return forMachinesInParallel(to, func(m *Machine) error {
+		if len(changed) > 0 {
+			if err := m.runtime.CopyArchiveTo(ctx, bytes.NewReader(archive.Bytes()), m.containerName, destDir, opts.Archive); err != nil {
+				return err
+			}
+			if err := m.setOwnership(ctx, joinAll(destDir, changed), opts.CopyOptions); err != nil {
+				return err
+			}
+		}
+		if len(deleted) > 0 {
+			return m.run(ctx, "rm", append([]string{"-rf", "--"}, joinAll(destDir, deleted)...)...)
+		}
+		return nil
+	})
+}
+
+// writeArchive writes a tar archive of the paths, relative to srcDir, and
+// all the files under them but the ignored ones. The empty path stands for
+// the whole srcDir.
+func writeArchive(w io.Writer, srcDir string, paths []string, ignore []string) error {
+	tw := tar.NewWriter(w)
+	written := map[string]bool{}
+	for _, rel := range paths {
+		root := filepath.Join(srcDir, filepath.FromSlash(rel))
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					// removed in the meantime
+					return nil
+				}
+				return err
+			}
+			r, err := filepath.Rel(srcDir, p)
+			if err != nil || r == "." {
+				return err
+			}
+			r = filepath.ToSlash(r)
+			if ignored(r, ignore) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if written[r] {
+				return nil
+			}
+			written[r] = true
+			return addToArchive(tw, p, r)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "can't archive %s", root)
+		}
+	}
+	return tw.Close()
+}
+
+// addToArchive writes the file at p into the archive, named name.
+func addToArchive(tw *tar.Writer, p string, name string) error {
+	info, err := os.Lstat(p)
+	if err != nil {
+		return err
+	}
+	link := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.CopyN(tw, f, hdr.Size)
+	return err
+}
+
+// ignored returns whether the slash separated relative path, or any of its
+// parent directories, matches any of the patterns, either as a whole or by
+// its base name.
+func ignored(rel string, patterns []string) bool {
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], "/")
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, prefix); matched {
+				return true
+			}
+			if matched, _ := path.Match(pattern, parts[i]); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnored(t *testing.T) {
+	patterns := []string{".git", "*.log", "build/out"}
+	assert.True(t, ignored(".git", patterns))
+	assert.True(t, ignored(".git/objects/ab", patterns))
+	assert.True(t, ignored("src/.git/HEAD", patterns))
+	assert.True(t, ignored("app.log", patterns))
+	assert.True(t, ignored("logs/app.log", patterns))
+	assert.True(t, ignored("build/out/app", patterns))
+	assert.False(t, ignored("build/app", patterns))
+	assert.False(t, ignored("src/main.go", patterns))
+	assert.False(t, ignored("src/main.go", nil))
+}
+
+func TestWriteArchive(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":         "package main",
+		"pkg/lib.go":      "package pkg",
+		"pkg/lib.log":     "debug",
+		".git/HEAD":       "ref: refs/heads/main",
+		"docs/README.md":  "# docs",
+		"docs/guide.md":   "# guide",
+		"docs/nested/a.b": "a",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+	ignore := []string{".git", "*.log"}
+
+	var archive bytes.Buffer
+	assert.NoError(t, writeArchive(&archive, dir, []string{""}, ignore))
+	assert.Equal(t, map[string]string{
+		"main.go":         "package main",
+		"pkg/":            "",
+		"pkg/lib.go":      "package pkg",
+		"docs/":           "",
+		"docs/README.md":  "# docs",
+		"docs/guide.md":   "# guide",
+		"docs/nested/":    "",
+		"docs/nested/a.b": "a",
+	}, tarEntries(t, archive.Bytes()))
+
+	archive.Reset()
+	assert.NoError(t, writeArchive(&archive, dir, []string{"docs/nested", "main.go", "docs/nested/a.b", "gone.txt"}, ignore))
+	assert.Equal(t, map[string]string{
+		"main.go":         "package main",
+		"docs/nested/":    "",
+		"docs/nested/a.b": "a",
+	}, tarEntries(t, archive.Bytes()))
+}
+
+func TestSyncSourceRemoved(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, fake := newFakeCluster(t)
+	assert.NoError(t, c.Create(ctx, CreateOptions{}))
+	m, err := c.GetMachineByMachineName("nodes-node0")
+	assert.NoError(t, err)
+	src := filepath.Join(t.TempDir(), "src")
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "dir"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "dir", "file"), []byte("content"), 0644))
+
+	watching := make(chan struct{})
+	var once bool
+	var commands []string
+	onExec := fake.OnExec
+	fake.OnExec = func(container string, command []string, stdin []byte) ([]byte, error) {
+		commands = append(commands, strings.Join(command, " "))
+		if !once && slices.Contains(command, "mkdir") {
+			once = true
+			close(watching)
+		}
+		return onExec(container, command, stdin)
+	}
+	done := make(chan error)
+	go func() {
+		done <- c.Sync(ctx, src, []*Machine{m}, "/root", SyncOptions{Delete: true, Batch: time.Millisecond})
+	}()
+	<-watching
+	assert.NoError(t, os.RemoveAll(src))
+
+	select {
+	case err = <-done:
+		assert.Error(t, err)
+	case <-ctx.Done():
+		t.Fatal("still syncing once the source is removed")
+	}
+	for _, command := range commands {
+		assert.False(t, strings.HasPrefix(command, "rm") && strings.HasSuffix(command, " /root"), command)
+	}
+
+	// nor is it pushed as removed
+	assert.Error(t, pushChanges(ctx, src, []string{""}, []*Machine{m}, "/root", SyncOptions{Delete: true}))
+	assert.Error(t, pushChanges(ctx, src, []string{"."}, []*Machine{m}, "/root", SyncOptions{Delete: true}))
+}
@@ -0,0 +1,62 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Timeouts bound the steps of the operations on the machines, so that an
+// unresponsive image registry, machine or SSH server doesn't hang them. A
+// zero timeout doesn't bound its step.
+type Timeouts struct {
+	// Pull bounds the pull of each image.
+	Pull time.Duration
+	// Start bounds the creation and the start of each machine.
+	Start time.Duration
+	// Provision bounds the provisioning of each started machine: its users,
+	// its keys and its certificates.
+	Provision time.Duration
+	// SSH bounds the wait for the SSH server of a machine to accept the
+	// connections.
+	SSH time.Duration
+}
+
+// DefaultTimeouts are the timeouts of the clusters, unless set otherwise.
+var DefaultTimeouts = Timeouts{
+	Pull:      10 * time.Minute,
+	Start:     2 * time.Minute,
+	Provision: 5 * time.Minute,
+	SSH:       10 * time.Second,
+}
+
+// withTimeout runs the step with the timeout, if any. The error of a step
+// timing out says so, and still matches context.DeadlineExceeded.
+func withTimeout(ctx context.Context, step string, timeout time.Duration, do func(context.Context) error) error {
+	if timeout <= 0 {
+		return do(ctx)
+	}
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := do(stepCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s timed out after %s: %w", step, timeout, context.DeadlineExceeded)
+	}
+	return err
+}
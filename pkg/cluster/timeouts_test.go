@@ -0,0 +1,73 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTimeout(t *testing.T) {
+	wait := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	err := withTimeout(context.Background(), "pulling image centos", time.Millisecond, wait)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+	assert.True(t, strings.HasPrefix(err.Error(), "pulling image centos timed out after 1ms"), "%v", err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = withTimeout(ctx, "pulling image centos", time.Hour, wait)
+	assert.Equal(t, context.Canceled, err)
+
+	done := errors.New("done")
+	err = withTimeout(context.Background(), "pulling image centos", 0, func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+		return done
+	})
+	assert.Equal(t, done, err)
+}
+
+func TestCreateCancelled(t *testing.T) {
+	c, fake := newFakeCluster(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	onExec := fake.OnExec
+	fake.OnExec = func(container string, command []string, stdin []byte) ([]byte, error) {
+		// interrupted while provisioning the first machine
+		cancel()
+		return onExec(container, command, stdin)
+	}
+
+	err := c.Create(ctx, CreateOptions{})
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+	assert.Len(t, fake.Containers(), 1)
+
+	statuses, err := c.Status(context.Background(), nil)
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 2) {
+		assert.Equal(t, Running, statuses[0].State)
+		assert.Equal(t, NotCreated, statuses[1].State)
+	}
+}
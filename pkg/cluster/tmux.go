@@ -0,0 +1,130 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/pkg/errors"
+)
+
+// tmuxPane is a pane of the tmux session opened by Multiplex.
+type tmuxPane struct {
+	title   string
+	command []string
+}
+
+// Multiplex opens a tmux session with one pane per machine, named after the
+// machine and running the command returned for it, and attaches to it. The
+// input typed in a pane goes to all of them if synchronize is true. The
+// session is named after the panes, so that it's reattached only if opened
+// for the same machines, commands and synchronization.
+func (c *Cluster) Multiplex(machines []*Machine, command func(*Machine) []string, synchronize bool) error {
+	if len(machines) < 1 {
+		return errors.New("no machine to open")
+	}
+	if _, err := exec.LookPath("tmux"); err != nil {
+		return errors.Wrap(err, "tmux is required to open a pane per machine")
+	}
+
+	var panes []tmuxPane
+	for _, m := range machines {
+		panes = append(panes, tmuxPane{title: m.machineName, command: command(m)})
+	}
+	session := tmuxSession(c.Name(), panes, synchronize)
+	if run("tmux", "has-session", "-t", "="+session) == nil {
+		c.logger().Infof("Session %s already exists, attaching to it", session)
+	} else {
+		c.logger().Infof("Opening session %s with %d panes", session, len(panes))
+		dir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		for _, args := range tmuxCommands(session, dir, panes, synchronize) {
+			if err := run("tmux", args...); err != nil {
+				return errors.Wrapf(err, "tmux %s", args[0])
+			}
+		}
+	}
+
+	attach := []string{"attach-session", "-t", "=" + session}
+	if os.Getenv("TMUX") != "" {
+		// already in tmux, nesting sessions is refused
+		attach = []string{"switch-client", "-t", "=" + session}
+	}
+	cmd := exec.Command("tmux", attach...)
+	cmd.SetStdin(os.Stdin)
+	cmd.SetStdout(os.Stdout)
+	cmd.SetStderr(os.Stderr)
+	return cmd.Run()
+}
+
+// tmuxSession returns the name of the session of the panes, like
+// "vind-cluster-1a2b3c4d", telling the sessions opened for different panes or
+// synchronization apart.
+func tmuxSession(cluster string, panes []tmuxPane, synchronize bool) string {
+	hash := sha256.New()
+	for _, pane := range panes {
+		hash.Write([]byte(pane.title + "\x00" + shellJoin(pane.command) + "\x00"))
+	}
+	hash.Write([]byte(strconv.FormatBool(synchronize)))
+	return "vind-" + cluster + "-" + hex.EncodeToString(hash.Sum(nil))[:8]
+}
+
+// tmuxCommands returns the tmux commands creating a detached session with the
+// panes tiled in a single window, their titles shown in the pane borders. The
+// panes start in the dir.
+func tmuxCommands(session string, dir string, panes []tmuxPane, synchronize bool) [][]string {
+	target := "=" + session
+	commands := [][]string{
+		{"new-session", "-d", "-s", session, "-n", session, "-c", dir, shellJoin(panes[0].command)},
+		{"set-option", "-p", "-t", target, "@vind_machine", panes[0].title},
+	}
+	for _, pane := range panes[1:] {
+		commands = append(commands,
+			[]string{"split-window", "-t", target, "-c", dir, shellJoin(pane.command)},
+			[]string{"set-option", "-p", "-t", target, "@vind_machine", pane.title},
+			// re-tile after each split, so that there is always room for the next one
+			[]string{"select-layout", "-t", target, "tiled"},
+		)
+	}
+	commands = append(commands,
+		[]string{"set-option", "-w", "-t", target, "pane-border-status", "top"},
+		[]string{"set-option", "-w", "-t", target, "pane-border-format", " #{@vind_machine} "},
+	)
+	if synchronize {
+		commands = append(commands, []string{"set-option", "-w", "-t", target, "synchronize-panes", "on"})
+	}
+	return commands
+}
+
+// shellJoin joins the args into a command line for sh, quoting them as needed.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=@:,+%") == "" {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,62 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTmuxCommands(t *testing.T) {
+	panes := []tmuxPane{
+		{title: "test-node0", command: []string{"vind", "ssh", "test-node0"}},
+		{title: "test-node1", command: []string{"vind", "ssh", "test-node1"}},
+	}
+	commands := tmuxCommands("vind-cluster", "/work", panes, false)
+	assert.Equal(t, []string{"new-session", "-d", "-s", "vind-cluster", "-n", "vind-cluster", "-c", "/work", "vind ssh test-node0"}, commands[0])
+	assert.Equal(t, []string{"set-option", "-p", "-t", "=vind-cluster", "@vind_machine", "test-node0"}, commands[1])
+	assert.Equal(t, []string{"split-window", "-t", "=vind-cluster", "-c", "/work", "vind ssh test-node1"}, commands[2])
+	assert.Equal(t, []string{"set-option", "-p", "-t", "=vind-cluster", "@vind_machine", "test-node1"}, commands[3])
+	assert.Equal(t, []string{"select-layout", "-t", "=vind-cluster", "tiled"}, commands[4])
+	for _, command := range commands {
+		assert.NotContains(t, command, "synchronize-panes")
+	}
+
+	commands = tmuxCommands("vind-cluster", "/work", panes, true)
+	assert.Equal(t, []string{"set-option", "-w", "-t", "=vind-cluster", "synchronize-panes", "on"}, commands[len(commands)-1])
+}
+
+func TestTmuxSession(t *testing.T) {
+	all := []tmuxPane{
+		{title: "test-node0", command: []string{"vind", "ssh", "test-node0"}},
+		{title: "test-node1", command: []string{"vind", "ssh", "test-node1"}},
+	}
+	session := tmuxSession("cluster", all, false)
+	assert.Regexp(t, `^vind-cluster-[0-9a-f]{8}$`, session)
+	assert.Equal(t, session, tmuxSession("cluster", all, false))
+	assert.NotEqual(t, session, tmuxSession("cluster", all, true))
+	assert.NotEqual(t, session, tmuxSession("cluster", all[1:], false))
+	assert.NotEqual(t, session, tmuxSession("other", all, false))
+	root := []tmuxPane{all[0], {title: "test-node1", command: []string{"vind", "ssh", "root@test-node1"}}}
+	assert.NotEqual(t, session, tmuxSession("cluster", root, false))
+}
+
+func TestShellJoin(t *testing.T) {
+	assert.Equal(t, "vind ssh test-node0 -c /tmp/vind.yaml", shellJoin([]string{"vind", "ssh", "test-node0", "-c", "/tmp/vind.yaml"}))
+	assert.Equal(t, "'/my dir/vind' -e 'ls -l' ''", shellJoin([]string{"/my dir/vind", "-e", "ls -l", ""}))
+	assert.Equal(t, `'it'\''s'`, shellJoin([]string{"it's"}))
+}
@@ -17,10 +17,13 @@ limitations under the License.
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
-	"github.com/brightzheng100/vind/pkg/utils"
 	"gopkg.in/yaml.v2"
 )
 
@@ -30,6 +33,8 @@ type Config struct {
 	Cluster Cluster `json:"cluster"`
 	// MachineSets describe the sets of machines we define in this cluster.
 	MachineSets []MachineSet `json:"machineSets"`
+	// LoadBalancers describe the load balancers in front of the MachineSets.
+	LoadBalancers []LoadBalancer `json:"loadBalancers,omitempty"`
 }
 
 // Cluster is a set of Machines.
@@ -42,8 +47,54 @@ type Cluster struct {
 	// This field is optional. If absent, machines are expected to have a public
 	// key defined.
 	PrivateKey string `json:"privateKey,omitempty"`
+	// KeyType is the type of the SSH key pair generated when PrivateKey doesn't
+	// exist yet. One of "ed25519", "rsa" or "ecdsa". Defaults to "ed25519".
+	KeyType string `json:"keyType,omitempty"`
+	// CertificateAuthority configures an optional SSH certificate authority for
+	// the cluster.
+	CertificateAuthority *CertificateAuthority `json:"certificateAuthority,omitempty"`
+	// Domain is the DNS domain of the machines, which are resolvable as
+	// <machine>.<cluster>.<domain> from each other. Defaults to "vind".
+	Domain string `json:"domain,omitempty"`
 }
 
+// CertificateAuthority is a per-cluster SSH certificate authority. Machines
+// trust the user certificates it signs and present host certificates signed by
+// it, so access can be granted without touching authorized_keys.
+type CertificateAuthority struct {
+	// Enabled controls whether the machines are configured with the certificate
+	// authority. Defaults to false.
+	Enabled bool `json:"enabled"`
+	// TTL is the validity of the user certificates signed for "vind ssh", as a
+	// duration like "30m" or "8h". Defaults to "1h".
+	TTL string `json:"ttl,omitempty"`
+}
+
+// DefaultDomain is the default DNS domain of the machines.
+const DefaultDomain = "vind"
+
+// validDomain matches the DNS domains, made of dot separated labels.
+var validDomain = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+// MachineDomain returns the DNS domain of the machines of the cluster, like
+// "cluster.vind".
+func (conf Cluster) MachineDomain() string {
+	domain := conf.Domain
+	if domain == "" {
+		domain = DefaultDomain
+	}
+	return strings.ToLower(conf.Name) + "." + domain
+}
+
+const (
+	// KeyTypeED25519 is the Ed25519 SSH key type.
+	KeyTypeED25519 = "ed25519"
+	// KeyTypeRSA is the 4096-bit RSA SSH key type.
+	KeyTypeRSA = "rsa"
+	// KeyTypeECDSA is the ECDSA P-256 SSH key type.
+	KeyTypeECDSA = "ecdsa"
+)
+
 // MachineSet are a set of machines following the same specification.
 type MachineSet struct {
 	// Name is the MachineSet's name. Defaults to "test"
@@ -70,23 +121,53 @@ func NewConfigFromFile(path string) (*Config, error) {
 	return NewConfigFromYAML(data)
 }
 
+// validate checks basic rules for Cluster's fields
+func (conf Cluster) validate() error {
+	switch conf.KeyType {
+	case "", KeyTypeED25519, KeyTypeRSA, KeyTypeECDSA:
+	default:
+		return fmt.Errorf("Cluster conf validation: key type %v is not valid, it should be one of %v, %v or %v", conf.KeyType, KeyTypeED25519, KeyTypeRSA, KeyTypeECDSA)
+	}
+	if conf.Domain != "" && !validDomain.MatchString(conf.Domain) {
+		return fmt.Errorf("Cluster conf validation: domain %v is not valid", conf.Domain)
+	}
+	if ca := conf.CertificateAuthority; ca != nil && ca.TTL != "" {
+		if ttl, err := time.ParseDuration(ca.TTL); err != nil || ttl <= 0 {
+			return fmt.Errorf("Cluster conf validation: certificate authority ttl %v is not a valid duration", ca.TTL)
+		}
+	}
+	return nil
+}
+
 // validate checks basic rules for MachineReplicas's fields
 func (conf MachineSet) validate() error {
 	return conf.Spec.validate()
 }
 
-// Validate checks basic rules for Config's fields
+// Validate checks basic rules for Config's fields, returning all the
+// violations at once.
 func (conf Config) Validate() error {
-	valid := true
+	var errs []error
+	if err := conf.Cluster.validate(); err != nil {
+		errs = append(errs, err)
+	}
 	for _, machine := range conf.MachineSets {
-		err := machine.validate()
-		if err != nil {
-			valid = false
-			utils.Logger.Fatalf(err.Error())
+		if err := machine.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	names := map[string]bool{}
+	for _, lb := range conf.LoadBalancers {
+		if err := lb.validate(conf.MachineSets); err != nil {
+			errs = append(errs, err)
+		}
+		if names[lb.Name] {
+			errs = append(errs, fmt.Errorf("LoadBalancer conf validation: %v is defined twice", lb.Name))
 		}
+		names[lb.Name] = true
 	}
-	if !valid {
-		return fmt.Errorf("Configuration file non valid")
+	if len(errs) > 0 {
+		return fmt.Errorf("Configuration file non valid: %w", errors.Join(errs...))
 	}
 	return nil
 }
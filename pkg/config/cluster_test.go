@@ -0,0 +1,51 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterDomain(t *testing.T) {
+	assert.Equal(t, "dev.vind", Cluster{Name: "Dev"}.MachineDomain())
+	assert.Equal(t, "dev.example.com", Cluster{Name: "dev", Domain: "example.com"}.MachineDomain())
+
+	assert.NoError(t, Cluster{Name: "dev", Domain: "example.com"}.validate())
+	assert.Error(t, Cluster{Name: "dev", Domain: "Example.com"}.validate())
+	assert.Error(t, Cluster{Name: "dev", Domain: "example..com"}.validate())
+	assert.Error(t, Cluster{Name: "dev", Domain: "-example.com"}.validate())
+}
+
+func TestConfigValidate(t *testing.T) {
+	machineSets := []MachineSet{{Name: "web", Spec: Machine{Name: "node%d"}}}
+	assert.NoError(t, Config{Cluster: Cluster{Name: "dev"}, MachineSets: machineSets}.Validate())
+
+	err := Config{
+		Cluster:     Cluster{Name: "dev", KeyType: "dsa"},
+		MachineSets: append(machineSets, MachineSet{Name: "db", Spec: Machine{Name: "db"}}),
+		LoadBalancers: []LoadBalancer{
+			{Name: "web", Port: 8080, TargetPort: 80, MachineSet: "web"},
+			{Name: "web", Port: 8081, TargetPort: 80, MachineSet: "web"},
+		},
+	}.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "key type dsa is not valid")
+		assert.Contains(t, err.Error(), "machine name db is not valid")
+		assert.Contains(t, err.Error(), "web is defined twice")
+	}
+}
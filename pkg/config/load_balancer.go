@@ -0,0 +1,101 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// AlgorithmRoundRobin spreads the connections over the backends in turn.
+	AlgorithmRoundRobin = "roundRobin"
+	// AlgorithmLeastConnections sends the connections to the backend with the
+	// fewest active ones.
+	AlgorithmLeastConnections = "leastConnections"
+	// AlgorithmRandom sends the connections to a random backend.
+	AlgorithmRandom = "random"
+)
+
+// LoadBalancer balances the TCP connections to a host port over the machines
+// of a MachineSet.
+type LoadBalancer struct {
+	// Name is the load balancer's name.
+	Name string `json:"name"`
+	// Address is the host address to listen to. Defaults to "127.0.0.1".
+	Address string `json:"address,omitempty"`
+	// Port is the host port to listen to.
+	Port uint16 `json:"port"`
+	// MachineSet is the name of the MachineSet whose machines are the backends.
+	MachineSet string `json:"machineSet"`
+	// TargetPort is the container port of the backends.
+	TargetPort uint16 `json:"targetPort"`
+	// Algorithm is one of "roundRobin", "leastConnections" or "random".
+	// Defaults to "roundRobin".
+	Algorithm string `json:"algorithm,omitempty"`
+	// HealthCheck enables active health checks of the backends. If absent,
+	// all the running machines are backends.
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+}
+
+// HealthCheck checks the backends of a load balancer by connecting to them.
+type HealthCheck struct {
+	// Port is the container port checked. Defaults to the target port.
+	Port uint16 `json:"port,omitempty"`
+	// Interval is the time between two checks, as a duration like "5s".
+	// Defaults to "5s".
+	Interval string `json:"interval,omitempty"`
+	// Timeout is the time a check can take, as a duration like "2s".
+	// Defaults to "2s".
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// validate checks basic rules for LoadBalancer's fields
+func (conf LoadBalancer) validate(machineSets []MachineSet) error {
+	if conf.Name == "" {
+		return fmt.Errorf("LoadBalancer conf validation: name is required")
+	}
+	if conf.Address != "" && net.ParseIP(conf.Address) == nil {
+		return fmt.Errorf("LoadBalancer conf validation: address %v of %v is not a valid IP", conf.Address, conf.Name)
+	}
+	if conf.Port == 0 || conf.TargetPort == 0 {
+		return fmt.Errorf("LoadBalancer conf validation: port and targetPort of %v are required", conf.Name)
+	}
+	found := false
+	for _, machineSet := range machineSets {
+		found = found || machineSet.Name == conf.MachineSet
+	}
+	if !found {
+		return fmt.Errorf("LoadBalancer conf validation: machineSet %v of %v doesn't exist", conf.MachineSet, conf.Name)
+	}
+	switch conf.Algorithm {
+	case "", AlgorithmRoundRobin, AlgorithmLeastConnections, AlgorithmRandom:
+	default:
+		return fmt.Errorf("LoadBalancer conf validation: algorithm %v of %v is not valid, it should be one of %v, %v or %v", conf.Algorithm, conf.Name, AlgorithmRoundRobin, AlgorithmLeastConnections, AlgorithmRandom)
+	}
+	if hc := conf.HealthCheck; hc != nil {
+		for _, d := range []string{hc.Interval, hc.Timeout} {
+			if d == "" {
+				continue
+			}
+			if duration, err := time.ParseDuration(d); err != nil || duration <= 0 {
+				return fmt.Errorf("LoadBalancer conf validation: health check duration %v of %v is not valid", d, conf.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadBalancerValidate(t *testing.T) {
+	machineSets := []MachineSet{{Name: "workers", Replicas: 2}}
+	lb := func(update func(*LoadBalancer)) LoadBalancer {
+		lb := LoadBalancer{Name: "web", Port: 8080, MachineSet: "workers", TargetPort: 80}
+		update(&lb)
+		return lb
+	}
+	tests := []struct {
+		name  string
+		lb    LoadBalancer
+		valid bool
+	}{
+		{"minimal", lb(func(lb *LoadBalancer) {}), true},
+		{"complete", lb(func(lb *LoadBalancer) {
+			lb.Address = "0.0.0.0"
+			lb.Algorithm = AlgorithmLeastConnections
+			lb.HealthCheck = &HealthCheck{Port: 8081, Interval: "10s", Timeout: "1s"}
+		}), true},
+		{"no name", lb(func(lb *LoadBalancer) { lb.Name = "" }), false},
+		{"bad address", lb(func(lb *LoadBalancer) { lb.Address = "localhost" }), false},
+		{"no port", lb(func(lb *LoadBalancer) { lb.Port = 0 }), false},
+		{"no target port", lb(func(lb *LoadBalancer) { lb.TargetPort = 0 }), false},
+		{"unknown machineSet", lb(func(lb *LoadBalancer) { lb.MachineSet = "masters" }), false},
+		{"bad algorithm", lb(func(lb *LoadBalancer) { lb.Algorithm = "sticky" }), false},
+		{"bad interval", lb(func(lb *LoadBalancer) { lb.HealthCheck = &HealthCheck{Interval: "often"} }), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.lb.validate(machineSets)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -18,9 +18,8 @@ package config
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
-
-	"github.com/brightzheng100/vind/pkg/utils"
 )
 
 // Machine is the machine configuration.
@@ -54,11 +53,47 @@ type Machine struct {
 	// PublicKey is the name of the public key to upload onto the machine for root
 	// SSH access.
 	PublicKey string `json:"publicKey,omitempty"`
+	// PublicKeys is a list of names of public keys to upload onto the machine
+	// for the SSH access of User, in addition to PublicKey. The keys are looked
+	// up in the key store, see "vind keys add". When neither PublicKey nor
+	// PublicKeys is set, the cluster public key is uploaded.
+	PublicKeys []string `json:"publicKeys,omitempty"`
+	// UserPublicKeys maps other users, which have to exist in the image, to the
+	// names of the public keys to upload onto the machine for their SSH access.
+	UserPublicKeys map[string][]string `json:"userPublicKeys,omitempty"`
+	// Users is the list of OS users to create on the machine at provisioning
+	// time, so the same image can serve both root and non-root logins.
+	Users []User `json:"users,omitempty"`
 
 	// Backend specifies the runtime backend for this machine
 	Backend string `json:"backend,omitempty"`
 }
 
+// SudoNoPasswd grants a user password-less sudo permissions.
+const SudoNoPasswd = "nopasswd"
+
+// User is an OS user created on a Machine at provisioning time. Existing users
+// are updated instead.
+type User struct {
+	// Name is the user name.
+	Name string `json:"name"`
+	// UID is the user ID. Allocated by the machine if 0.
+	UID int `json:"uid,omitempty"`
+	// Groups is the list of supplementary groups of the user. Missing groups are
+	// created.
+	Groups []string `json:"groups,omitempty"`
+	// Shell is the login shell of the user. Defaults to "/bin/bash" for new users,
+	// and is kept for existing ones.
+	Shell string `json:"shell,omitempty"`
+	// Sudo grants sudo permissions to the user. Only "nopasswd" is supported,
+	// for password-less sudo.
+	Sudo string `json:"sudo,omitempty"`
+	// AuthorizedKeys is the list of names of public keys, in the key store, to
+	// upload onto the machine for the SSH access of the user. The machine user
+	// gets the cluster public key if no key is set.
+	AuthorizedKeys []string `json:"authorizedKeys,omitempty"`
+}
+
 // Volume is a volume that can be attached to a Machine.
 type Volume struct {
 	// Type is the volume type. One of "bind" or "volume".
@@ -91,12 +126,41 @@ type PortMapping struct {
 	ContainerPort uint16 `json:"containerPort"`
 }
 
+// validUserName matches the valid user and group names.
+var validUserName = regexp.MustCompile(`^[a-z_][a-z0-9_-]*\$?$`)
+
 // validate checks basic rules for Machine's fields
 func (conf Machine) validate() error {
 	validName := strings.Contains(conf.Name, "%d")
 	if !validName {
-		utils.Logger.Warnf("Machine conf validation: machine name %v is not valid, it should contains %%d", conf.Name)
-		return fmt.Errorf("Machine configuration not valid")
+		return fmt.Errorf("Machine conf validation: machine name %v is not valid, it should contains %%d", conf.Name)
+	}
+	for _, user := range conf.Users {
+		if err := user.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks basic rules for User's fields
+func (conf User) validate() error {
+	if !validUserName.MatchString(conf.Name) {
+		return fmt.Errorf("User conf validation: user name %v is not valid", conf.Name)
+	}
+	for _, group := range conf.Groups {
+		if !validUserName.MatchString(group) {
+			return fmt.Errorf("User conf validation: group name %v of user %v is not valid", group, conf.Name)
+		}
+	}
+	if conf.Shell != "" && (!strings.HasPrefix(conf.Shell, "/") || strings.ContainsAny(conf.Shell, " \t'\"$`;")) {
+		return fmt.Errorf("User conf validation: shell %v of user %v is not valid, it should be an absolute path", conf.Shell, conf.Name)
+	}
+	if conf.Sudo != "" && conf.Sudo != SudoNoPasswd {
+		return fmt.Errorf("User conf validation: sudo %v of user %v is not valid, it should be %v", conf.Sudo, conf.Name, SudoNoPasswd)
+	}
+	if conf.UID < 0 {
+		return fmt.Errorf("User conf validation: uid %v of user %v is not valid", conf.UID, conf.Name)
 	}
 	return nil
 }
@@ -0,0 +1,49 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		user  User
+		valid bool
+	}{
+		{"minimal", User{Name: "ubuntu"}, true},
+		{"complete", User{Name: "alice", UID: 1500, Groups: []string{"docker"}, Shell: "/bin/zsh", Sudo: SudoNoPasswd}, true},
+		{"bad name", User{Name: "alice; rm -rf /"}, false},
+		{"bad group", User{Name: "alice", Groups: []string{"Docker Users"}}, false},
+		{"relative shell", User{Name: "alice", Shell: "zsh"}, false},
+		{"bad sudo", User{Name: "alice", Sudo: "always"}, false},
+		{"bad uid", User{Name: "alice", UID: -1}, false},
+	}
+
+	for _, utest := range tests {
+		t.Run(utest.name, func(t *testing.T) {
+			err := utest.user.validate()
+			if utest.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -19,25 +19,73 @@ limitations under the License.
 package docker
 
 import (
+	"bytes"
+	"context"
+	"io"
+
 	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/pkg/errors"
 )
 
-// CopyTo copies the file at hostPath to the container at destPath
-func CopyTo(srcPath, containerNameOrID, destPath string) error {
-	cmd := exec.Command(
-		"docker", "cp",
+// CopyTo copies the file at hostPath to the container at destPath, keeping
+// the uid/gid of the files if archive is true
+func CopyTo(ctx context.Context, srcPath, containerNameOrID, destPath string, archive bool) error {
+	args := []string{"cp"}
+	if archive {
+		args = append(args, "-a")
+	}
+	args = append(args,
 		srcPath,                        // from the source file
 		containerNameOrID+":"+destPath, // to the node, at dest
 	)
-	return cmd.Run()
+	return exec.CommandContext(ctx, "docker", args...).Run()
 }
 
-// CopyFrom copies the file or dir in the container at srcPath to the host at hostPath
-func CopyFrom(containerNameOrID, srcPath, destPath string) error {
-	cmd := exec.Command(
-		"docker", "cp",
+// CopyFrom copies the file or dir in the container at srcPath to the host at hostPath,
+// keeping the uid/gid of the files if archive is true
+func CopyFrom(ctx context.Context, containerNameOrID, srcPath, destPath string, archive bool) error {
+	args := []string{"cp"}
+	if archive {
+		args = append(args, "-a")
+	}
+	args = append(args,
 		containerNameOrID+":"+srcPath, // from the node, at src
 		destPath,                      // to the host
 	)
-	return cmd.Run()
+	return exec.CommandContext(ctx, "docker", args...).Run()
+}
+
+// CopyArchiveTo extracts the tar archive read from r into the directory at
+// destPath in the container, keeping the uid/gid recorded in the archive if
+// archive is true
+func CopyArchiveTo(ctx context.Context, r io.Reader, containerNameOrID, destPath string, archive bool) error {
+	args := []string{"cp"}
+	if archive {
+		args = append(args, "-a")
+	}
+	args = append(args, "-", containerNameOrID+":"+destPath)
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.SetStdin(r)
+	return runCopy(cmd)
+}
+
+// CopyArchiveFrom writes a tar archive of the file or dir in the container at
+// srcPath to w
+func CopyArchiveFrom(ctx context.Context, containerNameOrID, srcPath string, w io.Writer) error {
+	cmd := exec.CommandContext(ctx, "docker", "cp", containerNameOrID+":"+srcPath, "-")
+	cmd.SetStdout(w)
+	return runCopy(cmd)
+}
+
+// runCopy runs the docker cp command, returning its error output on failure
+func runCopy(cmd exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.SetStderr(&stderr)
+	if err := cmd.Run(); err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			return errors.Wrap(err, string(msg))
+		}
+		return err
+	}
+	return nil
 }
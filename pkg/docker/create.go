@@ -19,6 +19,8 @@ limitations under the License.
 package docker
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 
 	"github.com/brightzheng100/vind/pkg/exec"
@@ -27,14 +29,14 @@ import (
 
 // Create creates a container with "docker create", with some error handling
 // it will return the ID of the created container if any, even on error
-func Create(image string, runArgs []string, containerArgs []string) (id string, err error) {
+func Create(ctx context.Context, image string, runArgs []string, containerArgs []string) (id string, err error) {
 	args := []string{"create"}
 	args = append(args, runArgs...)
 	args = append(args, image)
 	args = append(args, containerArgs...)
 
 	utils.Logger.Debug("Docker command: ", "docker", args)
-	cmd := exec.Command("docker", args...)
+	cmd := exec.CommandContext(ctx, "docker", args...)
 
 	output, err := exec.CombinedOutputLines(cmd)
 	if err != nil {
@@ -44,6 +46,10 @@ func Create(image string, runArgs []string, containerArgs []string) (id string,
 		}
 		return "", err
 	}
+	// dry runs create nothing
+	if exec.DryRun() {
+		return "", nil
+	}
 	// if docker created a container the id will be the first line and match
 	// validate the output and get the id
 	if len(output) < 1 {
@@ -0,0 +1,97 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/docker/docker/api/types/events"
+)
+
+// ContainerEvent is an event of a container, as reported by "docker events".
+type ContainerEvent struct {
+	Time      time.Time
+	Container string
+	// Action is what happened, like "start", "die" or
+	// "health_status: healthy".
+	Action string
+	// Attributes are the labels of the container, along with attributes of
+	// the event, like "exitCode".
+	Attributes map[string]string
+}
+
+// ContainerEvents streams the events of the containers matching all the
+// filters, like "label=creator=vind", as in "docker events --filter", until
+// the context is done. The error channel gets the error ending the stream
+// early, if any, then both channels are closed.
+func ContainerEvents(ctx context.Context, filters ...string) (<-chan ContainerEvent, <-chan error) {
+	args := []string{"events", "--format", "{{json .}}", "--filter", "type=container"}
+	for _, filter := range filters {
+		args = append(args, "--filter", filter)
+	}
+	out, in := io.Pipe()
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.SetStdout(in)
+	go func() {
+		in.CloseWithError(cmd.Run())
+	}()
+
+	eventsCh := make(chan ContainerEvent)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(eventsCh)
+		defer out.Close()
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			var message events.Message
+			if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+				errs <- fmt.Errorf("can't parse docker event %q: %v", scanner.Text(), err)
+				return
+			}
+			event := ContainerEvent{
+				Time:       time.Unix(0, message.TimeNano),
+				Container:  message.Actor.Attributes["name"],
+				Action:     message.Action,
+				Attributes: message.Actor.Attributes,
+			}
+			if message.TimeNano == 0 {
+				event.Time = time.Unix(message.Time, 0)
+			}
+			select {
+			case eventsCh <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err := scanner.Err(); err != nil {
+			errs <- err
+		} else {
+			errs <- fmt.Errorf("docker events ended")
+		}
+	}()
+	return eventsCh, errs
+}
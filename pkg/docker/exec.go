@@ -19,25 +19,31 @@ limitations under the License.
 package docker
 
 import (
+	"context"
 	"io"
+	"os"
 
 	"github.com/brightzheng100/vind/pkg/exec"
 )
 
 // containerCmder implements exec.Cmder for docker containers
 type containerCmder struct {
+	ctx      context.Context
 	nameOrID string
 }
 
-// ContainerCmder creates a new exec.Cmder against a docker container
-func ContainerCmder(containerNameOrID string) exec.Cmder {
+// ContainerCmder creates a new exec.Cmder against a docker container, its
+// commands being killed when the context is done
+func ContainerCmder(ctx context.Context, containerNameOrID string) exec.Cmder {
 	return &containerCmder{
+		ctx:      ctx,
 		nameOrID: containerNameOrID,
 	}
 }
 
 func (c *containerCmder) Command(command string, args ...string) exec.Cmd {
 	return &containerCmd{
+		ctx:      c.ctx,
 		nameOrID: c.nameOrID,
 		command:  command,
 		args:     args,
@@ -46,6 +52,7 @@ func (c *containerCmder) Command(command string, args ...string) exec.Cmd {
 
 // containerCmd implements exec.Cmd for docker containers
 type containerCmd struct {
+	ctx      context.Context
 	nameOrID string // the container name or ID
 	command  string
 	args     []string
@@ -68,7 +75,8 @@ func (c *containerCmd) Run() error {
 			"-i", // interactive so we can supply input
 		)
 	}
-	if c.stderr != nil || c.stdout != nil {
+	// docker refuses to attach a tty to input streamed from a non-terminal
+	if (c.stderr != nil || c.stdout != nil) && (c.stdin == nil || IsTerminal(c.stdin)) {
 		args = append(args,
 			"-t", // use a tty so we can get output
 		)
@@ -89,7 +97,7 @@ func (c *containerCmd) Run() error {
 		// finally, with the caller args
 		c.args...,
 	)
-	cmd := exec.Command("docker", args...)
+	cmd := exec.CommandContext(c.ctx, "docker", args...)
 	if c.stdin != nil {
 		cmd.SetStdin(c.stdin)
 	}
@@ -117,3 +125,13 @@ func (c *containerCmd) SetStdout(w io.Writer) {
 func (c *containerCmd) SetStderr(w io.Writer) {
 	c.stderr = w
 }
+
+// IsTerminal returns whether the reader is a terminal device.
+func IsTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -19,6 +19,7 @@ limitations under the License.
 package docker
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -27,8 +28,8 @@ import (
 )
 
 // Inspect return low-level information on containers
-func Inspect(containerNameOrID, format string) ([]string, error) {
-	cmd := exec.Command("docker", "inspect",
+func Inspect(ctx context.Context, containerNameOrID, format string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "docker", "inspect",
 		"-f", // format
 		fmt.Sprintf("'%s'", format),
 		containerNameOrID, // ... against the "node" container
@@ -39,8 +40,8 @@ func Inspect(containerNameOrID, format string) ([]string, error) {
 }
 
 // InspectObject is similar to Inspect but deserializes the JSON output to a struct.
-func InspectObject(containerNameOrID, format string, out interface{}) error {
-	res, err := Inspect(containerNameOrID, fmt.Sprintf("{{json %s}}", format))
+func InspectObject(ctx context.Context, containerNameOrID, format string, out interface{}) error {
+	res, err := Inspect(ctx, containerNameOrID, fmt.Sprintf("{{json %s}}", format))
 	if err != nil {
 		return err
 	}
@@ -19,11 +19,13 @@ limitations under the License.
 package docker
 
 import (
+	"context"
+
 	"github.com/brightzheng100/vind/pkg/exec"
 )
 
 // Kill sends the named signal to the container
-func Kill(signal, containerNameOrID string) error {
+func Kill(ctx context.Context, signal, containerNameOrID string) error {
 	cmd := exec.Command(
 		"docker", "kill",
 		"-s", signal,
@@ -0,0 +1,31 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package docker
+
+import (
+	"context"
+	"io"
+
+	"github.com/brightzheng100/vind/pkg/exec"
+)
+
+// Logs writes the logs of a container, stdout and stderr, to w.
+func Logs(ctx context.Context, container string, w io.Writer) error {
+	cmd := exec.CommandContext(ctx, "docker", "logs", container)
+	cmd.SetStdout(w)
+	cmd.SetStderr(w)
+	return cmd.Run()
+}
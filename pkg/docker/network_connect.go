@@ -19,18 +19,24 @@ limitations under the License.
 package docker
 
 import (
+	"context"
+
 	"github.com/brightzheng100/vind/pkg/exec"
 )
 
 // ConnectNetwork connects network to container.
-func ConnectNetwork(container, network string) error {
-	cmd := exec.Command("docker", "network", "connect", network, container)
+func ConnectNetwork(ctx context.Context, container, network string) error {
+	cmd := exec.CommandContext(ctx, "docker", "network", "connect", network, container)
 	return runWithLogging(cmd)
 }
 
-// ConnectNetworkWithAlias connects network to container adding a network-scoped
-// alias for the container.
-func ConnectNetworkWithAlias(container, network, alias string) error {
-	cmd := exec.Command("docker", "network", "connect", network, container, "--alias", alias)
+// ConnectNetworkWithAlias connects network to container adding network-scoped
+// aliases for the container.
+func ConnectNetworkWithAlias(ctx context.Context, container, network string, aliases ...string) error {
+	args := []string{"network", "connect", network, container}
+	for _, alias := range aliases {
+		args = append(args, "--alias", alias)
+	}
+	cmd := exec.CommandContext(ctx, "docker", args...)
 	return runWithLogging(cmd)
 }
@@ -0,0 +1,62 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"strings"
+
+	"github.com/brightzheng100/vind/pkg/exec"
+)
+
+// ContainerSummary is a container listed by ListContainers.
+type ContainerSummary struct {
+	Name   string
+	Labels map[string]string
+}
+
+// ListContainers lists the running containers matching all the filters, like
+// "label=creator=vind", as in "docker ps --filter"
+func ListContainers(ctx context.Context, filters ...string) ([]ContainerSummary, error) {
+	args := []string{"ps", "--format", "{{.Names}}\t{{.Labels}}"}
+	for _, filter := range filters {
+		args = append(args, "--filter", filter)
+	}
+	lines, err := exec.CombinedOutputLines(exec.CommandContext(ctx, "docker", args...))
+	if err != nil {
+		return nil, err
+	}
+	return parseContainerSummaries(lines), nil
+}
+
+func parseContainerSummaries(lines []string) []ContainerSummary {
+	var containers []ContainerSummary
+	for _, line := range lines {
+		name, labels, _ := strings.Cut(line, "\t")
+		if name == "" {
+			continue
+		}
+		c := ContainerSummary{Name: name, Labels: map[string]string{}}
+		for _, label := range strings.Split(labels, ",") {
+			if k, v, ok := strings.Cut(label, "="); ok {
+				c.Labels[k] = v
+			}
+		}
+		containers = append(containers, c)
+	}
+	return containers
+}
@@ -19,6 +19,7 @@ limitations under the License.
 package docker
 
 import (
+	"context"
 	"os"
 	"time"
 
@@ -29,30 +30,34 @@ import (
 // PullIfNotPresent will pull an image if it is not present locally
 // retrying up to retries times
 // it returns true if it attempted to pull, and any errors from pulling
-func PullIfNotPresent(image string, retries int) (pulled bool, err error) {
+func PullIfNotPresent(ctx context.Context, image string, retries int) (pulled bool, err error) {
 	// TODO(bentheelder): switch most (all) of the logging here to debug level
 	// once we have configurable log levels
 	// if this did not return an error, then the image exists locally
-	cmd := exec.Command("docker", "inspect", "--type=image", image)
+	cmd := exec.CommandContext(ctx, "docker", "inspect", "--type=image", image)
 	if err := cmd.Run(); err == nil {
 		utils.Logger.Infof("Docker Image: %s present locally", image)
 		return false, nil
 	}
 	// otherwise try to pull it
-	return true, Pull(image, retries)
+	return true, Pull(ctx, image, retries)
 }
 
 // Pull pulls an image, retrying up to retries times
-func Pull(image string, retries int) error {
+func Pull(ctx context.Context, image string, retries int) error {
 	utils.Logger.Infof("Pulling image: %s ...", image)
-	err := setPullCmd(image).Run()
+	err := setPullCmd(ctx, image).Run()
 	// retry pulling up to retries times if necessary
 	if err != nil {
 		for i := 0; i < retries; i++ {
-			time.Sleep(time.Second * time.Duration(i+1))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second * time.Duration(i+1)):
+			}
 			utils.Logger.WithError(err).Infof("Trying again to pull image: %s ...", image)
 			// TODO(bentheelder): add some backoff / sleep?
-			if err = setPullCmd(image).Run(); err == nil {
+			if err = setPullCmd(ctx, image).Run(); err == nil {
 				break
 			}
 		}
@@ -64,8 +69,8 @@ func Pull(image string, retries int) error {
 }
 
 // IsRunning checks if Docker is running properly
-func IsRunning() error {
-	cmd := exec.Command("docker", "version")
+func IsRunning(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "docker", "version")
 	if err := cmd.Run(); err != nil {
 		utils.Logger.WithError(err).Infoln("Cannot connect to the Docker daemon. Is the docker daemon running?")
 		return err
@@ -73,8 +78,8 @@ func IsRunning() error {
 	return nil
 }
 
-func setPullCmd(image string) exec.Cmd {
-	cmd := exec.Command("docker", "pull", image)
+func setPullCmd(ctx context.Context, image string) exec.Cmd {
+	cmd := exec.CommandContext(ctx, "docker", "pull", image)
 	cmd.SetStderr(os.Stderr)
 	return cmd
 }
@@ -0,0 +1,50 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+// queries are the docker commands which only read the state of docker.
+var queries = map[string]bool{
+	"events":  true,
+	"images":  true,
+	"info":    true,
+	"inspect": true,
+	"logs":    true,
+	"ps":      true,
+	"version": true,
+}
+
+// objects are the docker management commands of the docker objects.
+var objects = map[string]bool{
+	"container": true,
+	"image":     true,
+	"network":   true,
+	"volume":    true,
+}
+
+// IsQuery returns whether a command is a docker command only reading the
+// state of docker, like "docker inspect" or "docker network ls", which can be
+// run in dry runs.
+func IsQuery(name string, args []string) bool {
+	if name != "docker" || len(args) == 0 {
+		return false
+	}
+	if queries[args[0]] {
+		return true
+	}
+	// management commands, like "docker image inspect"
+	return len(args) > 1 && objects[args[0]] && (args[1] == "inspect" || args[1] == "ls")
+}
@@ -19,6 +19,7 @@ limitations under the License.
 package docker
 
 import (
+	"context"
 	"regexp"
 
 	"github.com/brightzheng100/vind/pkg/utils"
@@ -32,12 +33,12 @@ var containerIDRegex = regexp.MustCompile("^[a-f0-9]+$")
 
 // Run creates a container with "docker run", with some error handling
 // it will return the ID of the created container if any, even on error
-func Run(image string, runArgs []string, containerArgs []string) (id string, err error) {
+func Run(ctx context.Context, image string, runArgs []string, containerArgs []string) (id string, err error) {
 	args := []string{"run"}
 	args = append(args, runArgs...)
 	args = append(args, image)
 	args = append(args, containerArgs...)
-	cmd := exec.Command("docker", args...)
+	cmd := exec.CommandContext(ctx, "docker", args...)
 	output, err := exec.CombinedOutputLines(cmd)
 	if err != nil {
 		// log error output if there was any
@@ -19,10 +19,12 @@ limitations under the License.
 package docker
 
 import (
+	"context"
+
 	"github.com/brightzheng100/vind/pkg/exec"
 )
 
 // Save saves image to dest, as in `docker save`
-func Save(image, dest string) error {
-	return exec.Command("docker", "save", "-o", dest, image).Run()
+func Save(ctx context.Context, image, dest string) error {
+	return exec.CommandContext(ctx, "docker", "save", "-o", dest, image).Run()
 }
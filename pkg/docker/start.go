@@ -19,6 +19,8 @@ limitations under the License.
 package docker
 
 import (
+	"context"
+
 	"github.com/brightzheng100/vind/pkg/exec"
 	"github.com/brightzheng100/vind/pkg/utils"
 )
@@ -36,7 +38,7 @@ func runWithLogging(cmd exec.Cmd) error {
 }
 
 // Start starts a container.
-func Start(container string) error {
-	cmd := exec.Command("docker", "start", container)
+func Start(ctx context.Context, container string) error {
+	cmd := exec.CommandContext(ctx, "docker", "start", container)
 	return runWithLogging(cmd)
 }
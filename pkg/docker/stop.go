@@ -19,11 +19,13 @@ limitations under the License.
 package docker
 
 import (
+	"context"
+
 	"github.com/brightzheng100/vind/pkg/exec"
 )
 
 // Stop stops a container.
-func Stop(container string) error {
-	cmd := exec.Command("docker", "stop", container)
+func Stop(ctx context.Context, container string) error {
+	cmd := exec.CommandContext(ctx, "docker", "stop", container)
 	return runWithLogging(cmd)
 }
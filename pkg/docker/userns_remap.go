@@ -19,14 +19,15 @@ limitations under the License.
 package docker
 
 import (
+	"context"
 	"strings"
 
 	"github.com/brightzheng100/vind/pkg/exec"
 )
 
 // UsernsRemap checks if userns-remap is enabled in dockerd
-func UsernsRemap() bool {
-	cmd := exec.Command("docker", "info", "--format", "'{{json .SecurityOptions}}'")
+func UsernsRemap(ctx context.Context) bool {
+	cmd := exec.CommandContext(ctx, "docker", "info", "--format", "'{{json .SecurityOptions}}'")
 	lines, err := exec.CombinedOutputLines(cmd)
 	if err != nil {
 		return false
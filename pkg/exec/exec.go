@@ -21,6 +21,7 @@ package exec
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"io"
 	"os"
 
@@ -43,18 +44,29 @@ type Cmder interface {
 	Command(string, ...string) Cmd
 }
 
+// ContextCmder is a Cmder which can also create commands killed when their
+// context is done
+type ContextCmder interface {
+	Cmder
+	CommandContext(context.Context, string, ...string) Cmd
+}
+
 // DefaultCmder is a LocalCmder instance used for convienience, packages
 // originally using os/exec.Command can instead use pkg/kind/exec.Command
-// which forwards to this instance
-// TODO(bentheelder): swap this for testing
+// which forwards to this instance. It is swapped for a Recorder in dry runs.
 // TODO(bentheelder): consider not using a global for this :^)
-var DefaultCmder = &LocalCmder{}
+var DefaultCmder ContextCmder = &LocalCmder{}
 
 // Command is a convience wrapper over DefaultCmder.Command
 func Command(command string, args ...string) Cmd {
 	return DefaultCmder.Command(command, args...)
 }
 
+// CommandContext is a convience wrapper over DefaultCmder.CommandContext
+func CommandContext(ctx context.Context, command string, args ...string) Cmd {
+	return DefaultCmder.CommandContext(ctx, command, args...)
+}
+
 // CommandWithLogging is a convience wrapper over Command
 // display any errors received by the executed command
 func CommandWithLogging(command string, args ...string) error {
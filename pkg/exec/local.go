@@ -19,19 +19,26 @@ limitations under the License.
 package exec
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	osexec "os/exec"
 	"strings"
+	"time"
 
 	"github.com/brightzheng100/vind/pkg/utils"
 	"github.com/pkg/errors"
 )
 
+// waitDelay is how long a killed command may keep its output open, like the
+// processes docker exec leaves behind, before Run returns.
+const waitDelay = 5 * time.Second
+
 // LocalCmd wraps os/exec.Cmd, implementing the kind/pkg/exec.Cmd interface
 type LocalCmd struct {
 	*osexec.Cmd
+	ctx context.Context
 }
 
 var _ Cmd = &LocalCmd{}
@@ -48,6 +55,17 @@ func (c *LocalCmder) Command(name string, arg ...string) Cmd {
 	}
 }
 
+// CommandContext returns a new exec.Cmd backed by Cmd, killed when the
+// context is done
+func (c *LocalCmder) CommandContext(ctx context.Context, name string, arg ...string) Cmd {
+	cmd := osexec.CommandContext(ctx, name, arg...)
+	cmd.WaitDelay = waitDelay
+	return &LocalCmd{
+		Cmd: cmd,
+		ctx: ctx,
+	}
+}
+
 // SetEnv sets env
 func (cmd *LocalCmd) SetEnv(env ...string) {
 	cmd.Env = env
@@ -68,10 +86,20 @@ func (cmd *LocalCmd) SetStderr(w io.Writer) {
 	cmd.Stderr = w
 }
 
-// Run runs
+// Run runs, returning the error of the context instead of the one of the
+// killed command if the context is done
 func (cmd *LocalCmd) Run() error {
 	utils.Logger.Debugf("Running: %v %v", cmd.Path, cmd.Args)
-	return cmd.Cmd.Run()
+	err := cmd.Cmd.Run()
+	if err != nil && cmd.ctx != nil && cmd.ctx.Err() != nil {
+		return cmd.ctx.Err()
+	}
+	return err
+}
+
+// LookPath searches for an executable named file in the directories of the PATH.
+func LookPath(file string) (string, error) {
+	return osexec.LookPath(file)
 }
 
 func ExecuteCommand(command string, args ...string) (string, error) {
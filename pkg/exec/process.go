@@ -0,0 +1,44 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"os"
+	osexec "os/exec"
+)
+
+// StartDetached starts a command in the background, detached from the
+// terminal so that it survives its parent, with its output appended to the
+// log file. It returns the pid of the started process.
+func StartDetached(logFile string, name string, args ...string) (int, error) {
+	log, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer log.Close()
+
+	cmd := osexec.Command(name, args...)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	setDetached(cmd)
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	pid := cmd.Process.Pid
+	// the process is not waited for, it's on its own
+	return pid, cmd.Process.Release()
+}
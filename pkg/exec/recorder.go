@@ -0,0 +1,207 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/brightzheng100/vind/pkg/utils"
+)
+
+// Recorded is a command recorded by a Recorder.
+type Recorded struct {
+	Name  string
+	Args  []string
+	Env   []string
+	Stdin []byte
+}
+
+// String returns the command as a shell command line, without its input.
+func (r Recorded) String() string {
+	words := make([]string, 0, len(r.Args)+len(r.Env)+2)
+	if len(r.Env) > 0 {
+		words = append(words, "env")
+		for _, env := range r.Env {
+			words = append(words, ShellQuote(env))
+		}
+	}
+	words = append(words, ShellQuote(r.Name))
+	for _, arg := range r.Args {
+		words = append(words, ShellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// Recorder is a ContextCmder recording the commands instead of running them,
+// for dry runs. The recorded commands succeed without any output.
+type Recorder struct {
+	// Cmder runs the commands accepted by Passthrough.
+	Cmder ContextCmder
+	// Passthrough returns whether a command only queries state, and is run
+	// rather than recorded. All the commands are recorded when nil.
+	Passthrough func(name string, args []string) bool
+
+	mu       sync.Mutex
+	commands []Recorded
+}
+
+var _ ContextCmder = &Recorder{}
+
+// Command returns a command recorded when run.
+func (r *Recorder) Command(name string, args ...string) Cmd {
+	return r.CommandContext(context.Background(), name, args...)
+}
+
+// CommandContext returns a command recorded when run, unless the context is
+// done.
+func (r *Recorder) CommandContext(ctx context.Context, name string, args ...string) Cmd {
+	if r.Passthrough != nil && r.Cmder != nil && r.Passthrough(name, args) {
+		return r.Cmder.CommandContext(ctx, name, args...)
+	}
+	return &recordedCmd{
+		recorder: r,
+		ctx:      ctx,
+		command:  Recorded{Name: name, Args: args},
+	}
+}
+
+// Commands returns the commands recorded so far, in order.
+func (r *Recorder) Commands() []Recorded {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Recorded(nil), r.commands...)
+}
+
+func (r *Recorder) record(command Recorded) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, command)
+}
+
+// recordedCmd is a command of a Recorder.
+type recordedCmd struct {
+	recorder *Recorder
+	ctx      context.Context
+	command  Recorded
+	stdin    io.Reader
+}
+
+var _ Cmd = &recordedCmd{}
+
+// Run records the command along with its whole input.
+func (cmd *recordedCmd) Run() error {
+	if err := cmd.ctx.Err(); err != nil {
+		return err
+	}
+	if cmd.stdin != nil {
+		input, err := io.ReadAll(cmd.stdin)
+		if err != nil {
+			return err
+		}
+		cmd.command.Stdin = input
+	}
+	utils.Logger.Infof("Dry run: %s", cmd.command)
+	cmd.recorder.record(cmd.command)
+	return nil
+}
+
+// SetEnv sets env
+func (cmd *recordedCmd) SetEnv(env ...string) {
+	cmd.command.Env = env
+}
+
+// SetStdin sets stdin
+func (cmd *recordedCmd) SetStdin(r io.Reader) {
+	cmd.stdin = r
+}
+
+// SetStdout is a no-op, recorded commands have no output
+func (cmd *recordedCmd) SetStdout(io.Writer) {}
+
+// SetStderr is a no-op, recorded commands have no output
+func (cmd *recordedCmd) SetStderr(io.Writer) {}
+
+// DryRun returns whether DefaultCmder records the commands instead of running
+// them.
+func DryRun() bool {
+	_, ok := DefaultCmder.(*Recorder)
+	return ok
+}
+
+// scriptDelimiter ends the here-documents of the scripts.
+const scriptDelimiter = "VIND_EOF"
+
+var safeWord = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// ShellQuote quotes a word for a POSIX shell, when needed.
+func ShellQuote(word string) string {
+	if safeWord.MatchString(word) {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+// WriteScript writes the commands as a standalone POSIX shell script, stopping
+// at the first failing command. Text inputs are passed as here-documents,
+// other inputs are decoded from base64.
+func WriteScript(w io.Writer, header string, commands []Recorded) error {
+	var b bytes.Buffer
+	b.WriteString("#!/bin/sh\n")
+	for _, line := range strings.Split(strings.TrimSpace(header), "\n") {
+		if line != "" {
+			fmt.Fprintf(&b, "# %s\n", line)
+		}
+	}
+	b.WriteString("set -e\n")
+	for _, command := range commands {
+		b.WriteString("\n")
+		switch input := command.Stdin; {
+		case input == nil:
+			fmt.Fprintf(&b, "%s\n", command)
+		case len(input) == 0:
+			fmt.Fprintf(&b, "%s </dev/null\n", command)
+		case isHereDocument(input):
+			fmt.Fprintf(&b, "%s <<'%s'\n%s%s\n", command, scriptDelimiter, input, scriptDelimiter)
+		default:
+			fmt.Fprintf(&b, "printf '%%s' %s | base64 -d | %s\n", base64.StdEncoding.EncodeToString(input), command)
+		}
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// isHereDocument returns whether an input can be passed verbatim in a quoted
+// here-document.
+func isHereDocument(input []byte) bool {
+	if len(input) == 0 || input[len(input)-1] != '\n' || !utf8.Valid(input) || bytes.IndexByte(input, 0) >= 0 {
+		return false
+	}
+	for _, line := range strings.Split(string(input), "\n") {
+		if line == scriptDelimiter {
+			return false
+		}
+	}
+	return true
+}
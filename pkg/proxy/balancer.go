@@ -0,0 +1,264 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/utils"
+)
+
+// Load balancing algorithms, as in the config.
+const (
+	RoundRobin       = "roundRobin"
+	LeastConnections = "leastConnections"
+	Random           = "random"
+)
+
+// ErrNoBackend is returned when no healthy backend is available.
+var ErrNoBackend = errors.New("no healthy backend")
+
+// Backend is a target of a Balancer.
+type Backend struct {
+	// Name identifies the backend, like the machine name.
+	Name string
+	// Dial opens a connection to the backend.
+	Dial func() (io.ReadWriteCloser, error)
+	// Check checks the health of the backend, if health checks are enabled.
+	Check func(ctx context.Context) error
+}
+
+type backend struct {
+	Backend
+	healthy bool
+	active  int
+}
+
+// Balancer balances TCP connections over backends, which can change over time.
+type Balancer struct {
+	algorithm string
+	// checked tells whether the backends are health checked, in which case
+	// the ones failing are skipped until a check passes again.
+	checked bool
+
+	mu       sync.Mutex
+	backends []*backend
+	next     int
+}
+
+// NewBalancer creates a Balancer using the algorithm, "roundRobin" by default.
+func NewBalancer(algorithm string, checked bool) *Balancer {
+	if algorithm == "" {
+		algorithm = RoundRobin
+	}
+	return &Balancer{algorithm: algorithm, checked: checked}
+}
+
+// SetBackends replaces the backends, keeping the state of the ones already
+// known, and returns the names of the added and removed ones.
+func (b *Balancer) SetBackends(backends []Backend) (added []string, removed []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	known := map[string]*backend{}
+	for _, be := range b.backends {
+		known[be.Name] = be
+	}
+	updated := make([]*backend, 0, len(backends))
+	for _, be := range backends {
+		if existing, ok := known[be.Name]; ok {
+			existing.Backend = be
+			updated = append(updated, existing)
+			delete(known, be.Name)
+			continue
+		}
+		updated = append(updated, &backend{Backend: be, healthy: true})
+		added = append(added, be.Name)
+	}
+	for name := range known {
+		removed = append(removed, name)
+	}
+	sort.Slice(updated, func(i, j int) bool { return updated[i].Name < updated[j].Name })
+	sort.Strings(removed)
+	b.backends = updated
+	return added, removed
+}
+
+// Backends returns the names of the backends, and whether they're healthy.
+func (b *Balancer) Backends() map[string]bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	backends := map[string]bool{}
+	for _, be := range b.backends {
+		backends[be.Name] = be.healthy
+	}
+	return backends
+}
+
+// pick chooses a healthy backend, other than the excluded ones, and counts
+// a connection to it.
+func (b *Balancer) pick(exclude map[string]bool) (*backend, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var candidates []*backend
+	for _, be := range b.backends {
+		if be.healthy && !exclude[be.Name] {
+			candidates = append(candidates, be)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoBackend
+	}
+
+	var picked *backend
+	switch b.algorithm {
+	case LeastConnections:
+		picked = candidates[0]
+		for _, be := range candidates[1:] {
+			if be.active < picked.active {
+				picked = be
+			}
+		}
+	case Random:
+		picked = candidates[rand.Intn(len(candidates))]
+	default:
+		picked = candidates[b.next%len(candidates)]
+		b.next++
+	}
+	picked.active++
+	return picked, nil
+}
+
+func (b *Balancer) release(be *backend) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	be.active--
+}
+
+func (b *Balancer) setHealthy(be *backend, healthy bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if be.healthy != healthy {
+		if healthy {
+			utils.Logger.Infof("Backend %s is healthy", be.Name)
+		} else {
+			utils.Logger.Warnf("Backend %s is unhealthy", be.Name)
+		}
+	}
+	be.healthy = healthy
+}
+
+// dial connects to a backend, trying the others if it fails.
+func (b *Balancer) dial() (*backend, io.ReadWriteCloser, error) {
+	tried := map[string]bool{}
+	for {
+		be, err := b.pick(tried)
+		if err != nil {
+			return nil, nil, err
+		}
+		conn, err := be.Dial()
+		if err == nil {
+			return be, conn, nil
+		}
+		utils.Logger.Warnf("Connecting to backend %s: %v", be.Name, err)
+		b.release(be)
+		tried[be.Name] = true
+		if b.checked {
+			// until the next health check passes
+			b.setHealthy(be, false)
+		}
+	}
+}
+
+// Serve accepts connections on the listener and forwards them to the
+// backends, until the context is done.
+func (b *Balancer) Serve(ctx context.Context, listener net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			be, target, err := b.dial()
+			if err != nil {
+				utils.Logger.Warnf("Balancing %s: %v", conn.RemoteAddr(), err)
+				return
+			}
+			defer b.release(be)
+			defer target.Close()
+			utils.Logger.Debugf("Balancing %s to %s", conn.RemoteAddr(), be.Name)
+			pipe(ctx, conn, target)
+		}()
+	}
+}
+
+// HealthCheck checks all the backends at every interval, until the context
+// is done.
+func (b *Balancer) HealthCheck(ctx context.Context, interval time.Duration, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		b.checkAll(ctx, timeout)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *Balancer) checkAll(ctx context.Context, timeout time.Duration) {
+	b.mu.Lock()
+	backends := append([]*backend(nil), b.backends...)
+	b.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, be := range backends {
+		if be.Check == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			err := be.Check(ctx)
+			if err != nil {
+				utils.Logger.Debugf("Health check of backend %s: %v", be.Name, err)
+			}
+			b.setHealthy(be, err == nil)
+		}()
+	}
+	wg.Wait()
+}
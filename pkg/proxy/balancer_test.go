@@ -0,0 +1,161 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nopConn struct{}
+
+func (nopConn) Read(p []byte) (int, error)  { return 0, io.EOF }
+func (nopConn) Write(p []byte) (int, error) { return len(p), nil }
+func (nopConn) Close() error                { return nil }
+
+func backendsOf(names ...string) []Backend {
+	var backends []Backend
+	for _, name := range names {
+		backends = append(backends, Backend{
+			Name: name,
+			Dial: func() (io.ReadWriteCloser, error) { return nopConn{}, nil },
+		})
+	}
+	return backends
+}
+
+func TestSetBackends(t *testing.T) {
+	b := NewBalancer("", false)
+	added, removed := b.SetBackends(backendsOf("node1", "node0"))
+	assert.Equal(t, []string{"node1", "node0"}, added)
+	assert.Empty(t, removed)
+
+	added, removed = b.SetBackends(backendsOf("node2", "node1"))
+	assert.Equal(t, []string{"node2"}, added)
+	assert.Equal(t, []string{"node0"}, removed)
+	assert.Equal(t, map[string]bool{"node1": true, "node2": true}, b.Backends())
+}
+
+func TestRoundRobin(t *testing.T) {
+	b := NewBalancer(RoundRobin, false)
+	b.SetBackends(backendsOf("node0", "node1", "node2"))
+	var picked []string
+	for i := 0; i < 4; i++ {
+		be, err := b.pick(nil)
+		assert.NoError(t, err)
+		picked = append(picked, be.Name)
+	}
+	assert.Equal(t, []string{"node0", "node1", "node2", "node0"}, picked)
+}
+
+func TestLeastConnections(t *testing.T) {
+	b := NewBalancer(LeastConnections, false)
+	b.SetBackends(backendsOf("node0", "node1"))
+	first, _ := b.pick(nil)
+	second, _ := b.pick(nil)
+	assert.NotEqual(t, first.Name, second.Name)
+
+	b.release(first)
+	third, _ := b.pick(nil)
+	assert.Equal(t, first.Name, third.Name)
+}
+
+func TestRandom(t *testing.T) {
+	b := NewBalancer(Random, false)
+	b.SetBackends(backendsOf("node0", "node1"))
+	for i := 0; i < 10; i++ {
+		be, err := b.pick(map[string]bool{"node0": true})
+		assert.NoError(t, err)
+		assert.Equal(t, "node1", be.Name)
+	}
+}
+
+func TestDialFailover(t *testing.T) {
+	backends := backendsOf("node0", "node1")
+	backends[0].Dial = func() (io.ReadWriteCloser, error) { return nil, errors.New("connection refused") }
+
+	b := NewBalancer(RoundRobin, true)
+	b.SetBackends(backends)
+	be, conn, err := b.dial()
+	assert.NoError(t, err)
+	assert.NotNil(t, conn)
+	assert.Equal(t, "node1", be.Name)
+	assert.Equal(t, map[string]bool{"node0": false, "node1": true}, b.Backends())
+
+	// node0 is skipped until a health check passes
+	b.release(be)
+	be, _, _ = b.dial()
+	assert.Equal(t, "node1", be.Name)
+
+	backends[0].Check = func(ctx context.Context) error { return nil }
+	b.SetBackends(backends)
+	b.checkAll(context.Background(), time.Second)
+	assert.Equal(t, map[string]bool{"node0": true, "node1": true}, b.Backends())
+
+	b.SetBackends(nil)
+	_, _, err = b.dial()
+	assert.Equal(t, ErrNoBackend, err)
+}
+
+func TestBalancerServe(t *testing.T) {
+	// backends answering with their name
+	var backends []Backend
+	for _, name := range []string{"node0", "node1"} {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer l.Close()
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				conn.Write([]byte(name))
+				conn.Close()
+			}
+		}()
+		addr := l.Addr().String()
+		backends = append(backends, Backend{Name: name, Dial: func() (io.ReadWriteCloser, error) { return net.Dial("tcp", addr) }})
+	}
+
+	b := NewBalancer(RoundRobin, false)
+	b.SetBackends(backends)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Serve(ctx, listener) }()
+
+	var answers []string
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		assert.NoError(t, err)
+		answer, err := io.ReadAll(conn)
+		assert.NoError(t, err)
+		answers = append(answers, string(answer))
+		conn.Close()
+	}
+	assert.Equal(t, []string{"node0", "node1"}, answers)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,240 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package proxy forwards TCP and UDP ports from the host to machines.
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// udpSessionTimeout is how long a UDP client is remembered without traffic.
+const udpSessionTimeout = time.Minute
+
+// Forward is a port forwarded from the host to a machine.
+type Forward struct {
+	HostIP        string `json:"hostIP,omitempty"`
+	HostPort      int    `json:"hostPort"`
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+}
+
+// String formats the forward like "[hostIP:]hostPort->containerPort[/udp]",
+// as the ports of the machines are displayed.
+func (f Forward) String() string {
+	s := fmt.Sprintf("%d->%d", f.HostPort, f.ContainerPort)
+	if f.HostIP != "" {
+		s = net.JoinHostPort(f.HostIP, s)
+	}
+	if f.Protocol != "tcp" {
+		s += "/" + f.Protocol
+	}
+	return s
+}
+
+// HostAddress returns the host address to listen to.
+func (f Forward) HostAddress() string {
+	return net.JoinHostPort(f.HostIP, strconv.Itoa(f.HostPort))
+}
+
+// ParseForward parses a forward like "[hostIP:][hostPort:]containerPort[/protocol]".
+// The host port is the container port if it's omitted, and any free port if it's
+// empty or 0, like in ":80".
+func ParseForward(spec string) (Forward, error) {
+	f := Forward{Protocol: "tcp"}
+	ports := spec
+	if i := strings.LastIndex(spec, "/"); i >= 0 {
+		ports, f.Protocol = spec[:i], strings.ToLower(spec[i+1:])
+	}
+	if f.Protocol != "tcp" && f.Protocol != "udp" {
+		return f, fmt.Errorf("unsupported protocol in %q, expected tcp or udp", spec)
+	}
+
+	parts := strings.Split(ports, ":")
+	if len(parts) > 3 {
+		return f, fmt.Errorf("bad port forward %q, expected [hostIP:][hostPort:]containerPort[/protocol]", spec)
+	}
+	var err error
+	if f.ContainerPort, err = parsePort(parts[len(parts)-1], false); err != nil {
+		return f, errors.Wrapf(err, "bad container port in %q", spec)
+	}
+	f.HostPort = f.ContainerPort
+	if len(parts) > 1 {
+		if f.HostPort, err = parsePort(parts[len(parts)-2], true); err != nil {
+			return f, errors.Wrapf(err, "bad host port in %q", spec)
+		}
+	}
+	if len(parts) > 2 {
+		if net.ParseIP(parts[0]) == nil {
+			return f, fmt.Errorf("bad host IP in %q", spec)
+		}
+		f.HostIP = parts[0]
+	}
+	return f, nil
+}
+
+func parsePort(s string, allowAny bool) (int, error) {
+	if s == "" && allowAny {
+		return 0, nil
+	}
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > 65535 || (port == 0 && !allowAny) {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+// Dialer opens a stream to the port of the machine, over the network, "tcp"
+// or "udp".
+type Dialer func(network string, port int) (io.ReadWriteCloser, error)
+
+// ServeTCP accepts connections on the listener and forwards them to the
+// port of the machine, until the context is done.
+func ServeTCP(ctx context.Context, listener net.Listener, port int, dial Dialer) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "accept connection")
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			target, err := dial("tcp", port)
+			if err != nil {
+				utils.Logger.Warnf("Forwarding %s to port %d: %v", conn.RemoteAddr(), port, err)
+				return
+			}
+			defer target.Close()
+			utils.Logger.Debugf("Forwarding %s to port %d", conn.RemoteAddr(), port)
+			pipe(ctx, conn, target)
+		}()
+	}
+}
+
+// pipe copies the data both ways until either side is closed, or the context
+// is done.
+func pipe(ctx context.Context, a io.ReadWriteCloser, b io.ReadWriteCloser) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// ServeUDP forwards the datagrams received on the connection to the port of
+// the machine, and the replies back to their clients, until the context is done.
+func ServeUDP(ctx context.Context, conn net.PacketConn, port int, dial Dialer) error {
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	var mu sync.Mutex
+	sessions := map[string]io.ReadWriteCloser{}
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, target := range sessions {
+			target.Close()
+		}
+	}()
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, client, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "read datagram")
+		}
+		mu.Lock()
+		target, ok := sessions[client.String()]
+		if !ok {
+			if target, err = dial("udp", port); err != nil {
+				mu.Unlock()
+				utils.Logger.Warnf("Forwarding %s to port %d: %v", client, port, err)
+				continue
+			}
+			sessions[client.String()] = target
+			go func() {
+				replyUDP(conn, client, target)
+				mu.Lock()
+				delete(sessions, client.String())
+				mu.Unlock()
+				target.Close()
+			}()
+		}
+		mu.Unlock()
+		if _, err := target.Write(buf[:n]); err != nil {
+			utils.Logger.Warnf("Forwarding %s to port %d: %v", client, port, err)
+		}
+	}
+}
+
+// replyUDP sends the replies of the target back to the client, until the
+// session times out.
+func replyUDP(conn net.PacketConn, client net.Addr, target io.ReadWriteCloser) {
+	buf := make([]byte, 64*1024)
+	for {
+		if deadline, ok := target.(interface{ SetReadDeadline(time.Time) error }); ok {
+			deadline.SetReadDeadline(time.Now().Add(udpSessionTimeout))
+		}
+		n, err := target.Read(buf)
+		if err != nil {
+			return
+		}
+		if _, err := conn.WriteTo(buf[:n], client); err != nil {
+			return
+		}
+	}
+}
+
+// NetDialer returns a Dialer connecting to the ports of the host, like the IP
+// of a machine.
+func NetDialer(host string) Dialer {
+	return func(network string, port int) (io.ReadWriteCloser, error) {
+		return net.DialTimeout(network, net.JoinHostPort(host, strconv.Itoa(port)), 10*time.Second)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseForward(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected Forward
+		fails    bool
+	}{
+		{spec: "80", expected: Forward{HostPort: 80, ContainerPort: 80, Protocol: "tcp"}},
+		{spec: "8080:80", expected: Forward{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}},
+		{spec: ":80", expected: Forward{HostPort: 0, ContainerPort: 80, Protocol: "tcp"}},
+		{spec: "5353:53/udp", expected: Forward{HostPort: 5353, ContainerPort: 53, Protocol: "udp"}},
+		{spec: "0.0.0.0:8080:80/TCP", expected: Forward{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}},
+		{spec: "80/sctp", fails: true},
+		{spec: "http", fails: true},
+		{spec: "8080:", fails: true},
+		{spec: "0", fails: true},
+		{spec: "70000", fails: true},
+		{spec: "localhost:8080:80", fails: true},
+		{spec: "1:2:3:4", fails: true},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			f, err := ParseForward(test.spec)
+			if test.fails {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, f)
+		})
+	}
+}
+
+func TestForwardString(t *testing.T) {
+	assert.Equal(t, "8080->80", Forward{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}.String())
+	assert.Equal(t, "127.0.0.1:5353->53/udp", Forward{HostIP: "127.0.0.1", HostPort: 5353, ContainerPort: 53, Protocol: "udp"}.String())
+}
+
+func TestServeTCP(t *testing.T) {
+	// an echo server standing for the machine
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- ServeTCP(ctx, listener, echo.Addr().(*net.TCPAddr).Port, NetDialer("127.0.0.1"))
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Write([]byte("ping"))
+	assert.NoError(t, err)
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(conn, reply)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(reply))
+
+	cancel()
+	assert.NoError(t, <-done)
+	conn.Close()
+}
+
+func TestServeUDP(t *testing.T) {
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- ServeUDP(ctx, conn, echo.LocalAddr().(*net.UDPAddr).Port, NetDialer("127.0.0.1"))
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	assert.NoError(t, err)
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	for _, msg := range []string{"ping", "pong"} {
+		_, err = client.Write([]byte(msg))
+		assert.NoError(t, err)
+		reply := make([]byte, 16)
+		n, err := client.Read(reply)
+		assert.NoError(t, err)
+		assert.Equal(t, msg, string(reply[:n]))
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,213 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/brightzheng100/vind/pkg/docker"
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+)
+
+// pullRetries is how many times the images are pulled again on failure.
+const pullRetries = 2
+
+// Docker is the Runtime of the docker CLI.
+type Docker struct{}
+
+var _ Runtime = Docker{}
+
+// IsRunning checks that the Docker daemon can be reached.
+func (Docker) IsRunning(ctx context.Context) error {
+	return docker.IsRunning(ctx)
+}
+
+// PullIfNotPresent pulls the image if it's not present yet.
+func (Docker) PullIfNotPresent(ctx context.Context, image string) error {
+	_, err := docker.PullIfNotPresent(ctx, image, pullRetries)
+	return err
+}
+
+// Create creates a container with "docker create".
+func (Docker) Create(ctx context.Context, image string, runArgs []string, command []string) error {
+	_, err := docker.Create(ctx, image, runArgs, command)
+	return err
+}
+
+// ConnectNetwork connects the container to the network, with the aliases if
+// any.
+func (Docker) ConnectNetwork(ctx context.Context, container string, network string, aliases ...string) error {
+	if len(aliases) < 1 {
+		return docker.ConnectNetwork(ctx, container, network)
+	}
+	return docker.ConnectNetworkWithAlias(ctx, container, network, aliases...)
+}
+
+// Start starts the container.
+func (Docker) Start(ctx context.Context, container string) error {
+	return docker.Start(ctx, container)
+}
+
+// Stop stops the container.
+func (Docker) Stop(ctx context.Context, container string) error {
+	return docker.Stop(ctx, container)
+}
+
+// Remove kills the container if it's running, and removes it with its
+// volumes.
+func (Docker) Remove(ctx context.Context, container string) error {
+	return exec.CommandContext(ctx, "docker", "rm", "--force", "--volumes", container).Run()
+}
+
+// Inspect returns the details of the container, or ErrNotFound.
+func (Docker) Inspect(ctx context.Context, container string) (*types.ContainerJSON, error) {
+	lines, err := docker.Inspect(ctx, container, "{{json .}}")
+	if err != nil {
+		if strings.Contains(strings.Join(lines, "\n"), "No such") {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, container)
+		}
+		return nil, errors.Wrapf(err, "can't inspect container %s", container)
+	}
+	if len(lines) < 1 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, container)
+	}
+	var details types.ContainerJSON
+	if err := json.Unmarshal([]byte(strings.Trim(lines[0], "'")), &details); err != nil {
+		return nil, errors.Wrapf(err, "can't parse the details of container %s", container)
+	}
+	return &details, nil
+}
+
+// List returns the running containers having all the labels.
+func (Docker) List(ctx context.Context, labels ...string) ([]docker.ContainerSummary, error) {
+	filters := make([]string, len(labels))
+	for i, label := range labels {
+		filters[i] = "label=" + label
+	}
+	return docker.ListContainers(ctx, filters...)
+}
+
+// Events streams the events of the containers having all the labels.
+func (Docker) Events(ctx context.Context, labels ...string) (<-chan docker.ContainerEvent, <-chan error) {
+	filters := make([]string, len(labels))
+	for i, label := range labels {
+		filters[i] = "label=" + label
+	}
+	return docker.ContainerEvents(ctx, filters...)
+}
+
+// Logs writes the logs of the container to w.
+func (Docker) Logs(ctx context.Context, container string, w io.Writer) error {
+	return docker.Logs(ctx, container, w)
+}
+
+// Cmder returns the commands run as root in the container.
+func (Docker) Cmder(ctx context.Context, container string) exec.Cmder {
+	return docker.ContainerCmder(ctx, container)
+}
+
+// Exec returns a command run in the container as the user, without a tty.
+func (Docker) Exec(ctx context.Context, container string, user string, name string, args ...string) exec.Cmd {
+	return &execCmd{ctx: ctx, container: container, user: user, command: append([]string{name}, args...)}
+}
+
+// Session returns an interactive command run in the container as the user,
+// through a tty if its input is a terminal.
+func (Docker) Session(ctx context.Context, container string, user string, name string, args ...string) exec.Cmd {
+	return &execCmd{ctx: ctx, container: container, user: user, command: append([]string{name}, args...), tty: true}
+}
+
+// CopyTo copies a host file or directory into the container.
+func (Docker) CopyTo(ctx context.Context, src string, container string, dest string, archive bool) error {
+	return docker.CopyTo(ctx, src, container, dest, archive)
+}
+
+// CopyFrom copies a file or directory of the container to the host.
+func (Docker) CopyFrom(ctx context.Context, container string, src string, dest string, archive bool) error {
+	return docker.CopyFrom(ctx, container, src, dest, archive)
+}
+
+// CopyArchiveTo extracts a tar archive into a directory of the container.
+func (Docker) CopyArchiveTo(ctx context.Context, r io.Reader, container string, destDir string, archive bool) error {
+	return docker.CopyArchiveTo(ctx, r, container, destDir, archive)
+}
+
+// CopyArchiveFrom writes a file or directory of the container as a tar
+// archive.
+func (Docker) CopyArchiveFrom(ctx context.Context, container string, src string, w io.Writer) error {
+	return docker.CopyArchiveFrom(ctx, container, src, w)
+}
+
+// execCmd is a command run by "docker exec", without a tty unless it's
+// allowed one.
+type execCmd struct {
+	ctx       context.Context
+	container string
+	user      string
+	command   []string
+	// tty attaches a tty when the input is a terminal.
+	tty    bool
+	env    []string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+var _ exec.Cmd = &execCmd{}
+
+func (c *execCmd) Run() error {
+	args := []string{"exec"}
+	if c.stdin != nil {
+		args = append(args, "-i")
+	}
+	if c.tty && docker.IsTerminal(c.stdin) {
+		args = append(args, "-t")
+	}
+	if c.user != "" {
+		args = append(args, "-u", c.user)
+	}
+	for _, env := range c.env {
+		args = append(args, "-e", env)
+	}
+	args = append(args, c.container)
+	cmd := exec.CommandContext(c.ctx, "docker", append(args, c.command...)...)
+	cmd.SetStdin(c.stdin)
+	cmd.SetStdout(c.stdout)
+	cmd.SetStderr(c.stderr)
+	return cmd.Run()
+}
+
+func (c *execCmd) SetEnv(env ...string) {
+	c.env = env
+}
+
+func (c *execCmd) SetStdin(r io.Reader) {
+	c.stdin = r
+}
+
+func (c *execCmd) SetStdout(w io.Writer) {
+	c.stdout = w
+}
+
+func (c *execCmd) SetStderr(w io.Writer) {
+	c.stderr = w
+}
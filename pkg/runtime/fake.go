@@ -0,0 +1,703 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package runtime
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brightzheng100/vind/pkg/docker"
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+)
+
+// firstFakeHostPort is the first host port given to the ports published
+// without one, like Docker's ephemeral ports.
+const firstFakeHostPort = 32768
+
+// Fake is an in-memory Runtime, for tests. The containers get IPs in their
+// networks and host ports for their published ports in order, and only
+// have them while they're running, like with Docker. The commands run in the
+// containers succeed without output, unless OnExec says otherwise.
+type Fake struct {
+	// Down makes IsRunning fail, as if the runtime couldn't be reached.
+	Down bool
+	// OnExec returns the output and the error of a command run in a
+	// container, given its input if any.
+	OnExec func(container string, command []string, stdin []byte) ([]byte, error)
+
+	mu         sync.Mutex
+	containers []*FakeContainer
+	networks   []string
+	nextIP     map[string]int
+	nextPort   int
+	pulled     []string
+	ops        []string
+	// subscriptions get the events of the containers.
+	subscriptions []*fakeSubscription
+}
+
+var _ Runtime = &Fake{}
+
+// FakeContainer is a container of the Fake runtime.
+type FakeContainer struct {
+	Name       string
+	Image      string
+	Command    []string
+	Hostname   string
+	Labels     map[string]string
+	Privileged bool
+	Running    bool
+	Mounts     []types.MountPoint
+	// Ports are the host ports of the published ports.
+	Ports nat.PortMap
+	// Networks are the networks the container is connected to, with its IP
+	// in each of them.
+	Networks map[string]*network.EndpointSettings
+	// Execs are the commands run in the container.
+	Execs [][]string
+	// Logs are the logs returned by Logs.
+	Logs string
+}
+
+// NewFake returns a Fake runtime without containers.
+func NewFake() *Fake {
+	return &Fake{nextIP: map[string]int{}, nextPort: firstFakeHostPort}
+}
+
+// Container returns the container, or nil if it doesn't exist.
+func (f *Fake) Container(name string) *FakeContainer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.find(name)
+}
+
+// Containers returns the containers, in their creation order.
+func (f *Fake) Containers() []*FakeContainer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.containers)
+}
+
+// Ops returns the operations done on the runtime, like "start node0".
+func (f *Fake) Ops() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.ops)
+}
+
+// Pulled returns the images pulled.
+func (f *Fake) Pulled() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.pulled)
+}
+
+func (f *Fake) find(name string) *FakeContainer {
+	for _, c := range f.containers {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// get returns the container, recording the operation, or ErrNotFound.
+func (f *Fake) get(op string, name string) (*FakeContainer, error) {
+	f.ops = append(f.ops, op+" "+name)
+	c := f.find(name)
+	if c == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return c, nil
+}
+
+// IsRunning fails if the runtime is Down.
+func (f *Fake) IsRunning(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if f.Down {
+		return errors.New("fake runtime is down")
+	}
+	return nil
+}
+
+// PullIfNotPresent records the image as pulled.
+func (f *Fake) PullIfNotPresent(ctx context.Context, image string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !slices.Contains(f.pulled, image) {
+		f.pulled = append(f.pulled, image)
+	}
+	return nil
+}
+
+// Create creates a stopped container, from the "docker create" arguments it
+// knows about.
+func (f *Fake) Create(ctx context.Context, image string, runArgs []string, command []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := &FakeContainer{
+		Image:    image,
+		Command:  command,
+		Labels:   map[string]string{},
+		Ports:    nat.PortMap{},
+		Networks: map[string]*network.EndpointSettings{},
+	}
+	firstNetwork := "bridge"
+	var aliases []string
+	for i := 0; i < len(runArgs); i++ {
+		arg := runArgs[i]
+		value := ""
+		switch arg {
+		case "--name", "--hostname", "--label", "--mount", "-p", "--network", "--network-alias", "--tmpfs":
+			if i+1 >= len(runArgs) {
+				return fmt.Errorf("fake: %s needs a value", arg)
+			}
+			i++
+			value = runArgs[i]
+		}
+		switch arg {
+		case "--name":
+			c.Name = value
+		case "--hostname":
+			c.Hostname = value
+		case "--label":
+			k, v, _ := strings.Cut(value, "=")
+			c.Labels[k] = v
+		case "--privileged":
+			c.Privileged = true
+		case "--mount":
+			c.Mounts = append(c.Mounts, fakeMount(value))
+		case "-p":
+			port, binding, err := f.publish(value)
+			if err != nil {
+				return err
+			}
+			c.Ports[port] = append(c.Ports[port], binding)
+		case "--network":
+			firstNetwork = value
+		case "--network-alias":
+			aliases = append(aliases, value)
+		}
+	}
+	f.ops = append(f.ops, "create "+c.Name)
+	if c.Name == "" {
+		return errors.New("fake: containers need a name")
+	}
+	if f.find(c.Name) != nil {
+		return fmt.Errorf("fake: container name %s is already in use", c.Name)
+	}
+	f.connect(c, firstNetwork, aliases)
+	f.containers = append(f.containers, c)
+	f.emit(c, "create", nil)
+	return nil
+}
+
+// fakeMount parses a --mount value, like "type=bind,src=/a,dst=/b,readonly".
+func fakeMount(value string) types.MountPoint {
+	m := types.MountPoint{RW: true}
+	for _, field := range strings.Split(value, ",") {
+		k, v, _ := strings.Cut(field, "=")
+		switch k {
+		case "type":
+			m.Type = mount.Type(v)
+		case "src", "source":
+			m.Source = v
+		case "dst", "destination", "target":
+			m.Destination = v
+		case "readonly", "ro":
+			m.RW = false
+		}
+	}
+	return m
+}
+
+// publish parses a -p value, like "127.0.0.1:2222:22/tcp", giving it the
+// next host port if it has none.
+func (f *Fake) publish(value string) (nat.Port, nat.PortBinding, error) {
+	spec, proto, _ := strings.Cut(value, "/")
+	if proto == "" {
+		proto = "tcp"
+	}
+	parts := strings.Split(spec, ":")
+	binding := nat.PortBinding{HostIP: "0.0.0.0"}
+	containerPort := parts[len(parts)-1]
+	switch len(parts) {
+	case 3:
+		binding.HostIP, binding.HostPort = parts[0], parts[1]
+	case 2:
+		binding.HostPort = parts[0]
+	}
+	if _, err := strconv.Atoi(containerPort); err != nil {
+		return "", binding, fmt.Errorf("fake: bad published port %s", value)
+	}
+	if binding.HostPort == "" {
+		binding.HostPort = strconv.Itoa(f.nextPort)
+		f.nextPort++
+	}
+	return nat.Port(containerPort + "/" + proto), binding, nil
+}
+
+// connect connects the container to the network, giving it the next IP of
+// the network, in 172.17.0.0/16 for the first one, and so on.
+func (f *Fake) connect(c *FakeContainer, networkName string, aliases []string) {
+	index := slices.Index(f.networks, networkName)
+	if index < 0 {
+		f.networks = append(f.networks, networkName)
+		index = len(f.networks) - 1
+	}
+	f.nextIP[networkName]++
+	host := f.nextIP[networkName] + 1
+	c.Networks[networkName] = &network.EndpointSettings{
+		NetworkID:   networkName,
+		Aliases:     aliases,
+		Gateway:     fmt.Sprintf("172.%d.0.1", 17+index),
+		IPAddress:   fmt.Sprintf("172.%d.%d.%d", 17+index, host/256, host%256),
+		IPPrefixLen: 16,
+	}
+}
+
+// ConnectNetwork connects the container to the network.
+func (f *Fake) ConnectNetwork(ctx context.Context, container string, networkName string, aliases ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.get("connect "+networkName, container)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.Networks[networkName]; ok {
+		return fmt.Errorf("fake: container %s is already connected to network %s", container, networkName)
+	}
+	f.connect(c, networkName, aliases)
+	return nil
+}
+
+// Start starts the container.
+func (f *Fake) Start(ctx context.Context, container string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.get("start", container)
+	if err != nil {
+		return err
+	}
+	c.Running = true
+	f.emit(c, "start", nil)
+	return nil
+}
+
+// Stop stops the container.
+func (f *Fake) Stop(ctx context.Context, container string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.get("stop", container)
+	if err != nil {
+		return err
+	}
+	if c.Running {
+		c.Running = false
+		f.emit(c, "die", map[string]string{"exitCode": "0"})
+	}
+	f.emit(c, "stop", nil)
+	return nil
+}
+
+// Remove removes the container.
+func (f *Fake) Remove(ctx context.Context, container string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.get("remove", container)
+	if err != nil {
+		return err
+	}
+	f.containers = slices.DeleteFunc(f.containers, func(other *FakeContainer) bool { return other == c })
+	if c.Running {
+		c.Running = false
+		f.emit(c, "die", map[string]string{"exitCode": "137"})
+	}
+	f.emit(c, "destroy", nil)
+	return nil
+}
+
+// Inspect returns the details of the container, like Docker does.
+func (f *Fake) Inspect(ctx context.Context, name string) (*types.ContainerJSON, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := f.find(name)
+	if c == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	status := "created"
+	if c.Running {
+		status = "running"
+	}
+	ports := nat.PortMap{}
+	networks := map[string]*network.EndpointSettings{}
+	for networkName, endpoint := range c.Networks {
+		copied := *endpoint
+		if !c.Running {
+			copied.IPAddress, copied.Gateway, copied.IPPrefixLen = "", "", 0
+		}
+		networks[networkName] = &copied
+	}
+	if c.Running {
+		for port, bindings := range c.Ports {
+			ports[port] = slices.Clone(bindings)
+		}
+	}
+	return &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:    c.Name,
+			Name:  "/" + c.Name,
+			Image: c.Image,
+			State: &types.ContainerState{Status: status, Running: c.Running},
+		},
+		Mounts: slices.Clone(c.Mounts),
+		Config: &container.Config{
+			Hostname: c.Hostname,
+			Image:    c.Image,
+			Cmd:      slices.Clone(c.Command),
+			Labels:   c.Labels,
+		},
+		NetworkSettings: &types.NetworkSettings{
+			NetworkSettingsBase: types.NetworkSettingsBase{Ports: ports},
+			Networks:            networks,
+		},
+	}, nil
+}
+
+// List returns the running containers having all the labels.
+func (f *Fake) List(ctx context.Context, labels ...string) ([]docker.ContainerSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var summaries []docker.ContainerSummary
+	for _, c := range f.containers {
+		if c.Running && hasLabels(c.Labels, labels) {
+			summaries = append(summaries, docker.ContainerSummary{Name: c.Name, Labels: c.Labels})
+		}
+	}
+	return summaries, nil
+}
+
+// hasLabels returns whether the labels include all the wanted ones, like
+// "creator=vind".
+func hasLabels(labels map[string]string, wanted []string) bool {
+	for _, label := range wanted {
+		k, v, _ := strings.Cut(label, "=")
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// fakeSubscription is a stream of events of the Fake runtime. Its events are
+// queued, so that the operations never wait for the readers.
+type fakeSubscription struct {
+	labels []string
+	mu     sync.Mutex
+	queue  []docker.ContainerEvent
+	wake   chan struct{}
+}
+
+// Events streams the events of the containers having all the labels, as
+// the operations happen, and as given to Emit.
+func (f *Fake) Events(ctx context.Context, labels ...string) (<-chan docker.ContainerEvent, <-chan error) {
+	events := make(chan docker.ContainerEvent)
+	errs := make(chan error, 1)
+	if err := ctx.Err(); err != nil {
+		errs <- err
+		close(events)
+		close(errs)
+		return events, errs
+	}
+	sub := &fakeSubscription{labels: labels, wake: make(chan struct{}, 1)}
+	f.mu.Lock()
+	f.subscriptions = append(f.subscriptions, sub)
+	f.mu.Unlock()
+
+	go func() {
+		defer close(errs)
+		defer close(events)
+		defer func() {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.subscriptions = slices.DeleteFunc(f.subscriptions, func(other *fakeSubscription) bool { return other == sub })
+		}()
+		for {
+			sub.mu.Lock()
+			queue := sub.queue
+			sub.queue = nil
+			sub.mu.Unlock()
+			for _, event := range queue {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-sub.wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, errs
+}
+
+// Emit sends an event of the container to the streams of events, like
+// "oom", "die" with the "exitCode" attribute or "health_status: unhealthy",
+// without changing the container.
+func (f *Fake) Emit(container string, action string, attributes map[string]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := f.find(container)
+	if c == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, container)
+	}
+	f.emit(c, action, attributes)
+	return nil
+}
+
+// emit queues an event of the container for the subscriptions, with the
+// labels and the name of the container in its attributes, like Docker does.
+func (f *Fake) emit(c *FakeContainer, action string, attributes map[string]string) {
+	event := docker.ContainerEvent{
+		Time:       time.Now(),
+		Container:  c.Name,
+		Action:     action,
+		Attributes: map[string]string{"name": c.Name, "image": c.Image},
+	}
+	for k, v := range c.Labels {
+		event.Attributes[k] = v
+	}
+	for k, v := range attributes {
+		event.Attributes[k] = v
+	}
+	for _, sub := range f.subscriptions {
+		if !hasLabels(c.Labels, sub.labels) {
+			continue
+		}
+		sub.mu.Lock()
+		sub.queue = append(sub.queue, event)
+		sub.mu.Unlock()
+		select {
+		case sub.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Logs writes the Logs of the container to w.
+func (f *Fake) Logs(ctx context.Context, container string, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	c, err := f.get("logs", container)
+	var logs string
+	if c != nil {
+		logs = c.Logs
+	}
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, logs)
+	return err
+}
+
+// Cmder returns the commands run as root in the container.
+func (f *Fake) Cmder(ctx context.Context, container string) exec.Cmder {
+	return &fakeCmder{fake: f, ctx: ctx, container: container}
+}
+
+// Exec returns a command run in the container.
+func (f *Fake) Exec(ctx context.Context, container string, user string, name string, args ...string) exec.Cmd {
+	return &fakeCmd{fake: f, ctx: ctx, container: container, command: append([]string{name}, args...)}
+}
+
+// Session returns a command run in the container, like Exec.
+func (f *Fake) Session(ctx context.Context, container string, user string, name string, args ...string) exec.Cmd {
+	return f.Exec(ctx, container, user, name, args...)
+}
+
+// run runs a command in the container, through OnExec.
+func (f *Fake) run(name string, command []string, stdin io.Reader, stdout io.Writer) error {
+	var input []byte
+	if stdin != nil {
+		var err error
+		if input, err = io.ReadAll(stdin); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	c, err := f.get("exec", name)
+	if err == nil && !c.Running {
+		err = fmt.Errorf("fake: container %s is not running", name)
+	}
+	if err == nil {
+		c.Execs = append(c.Execs, command)
+	}
+	onExec := f.OnExec
+	f.mu.Unlock()
+	if err != nil || onExec == nil {
+		return err
+	}
+
+	output, err := onExec(name, command, input)
+	if stdout != nil && len(output) > 0 {
+		if _, werr := stdout.Write(output); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return err
+}
+
+// CopyTo records the copy.
+func (f *Fake) CopyTo(ctx context.Context, src string, container string, dest string, archive bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := f.get("copy "+src+" to "+dest, container)
+	return err
+}
+
+// CopyFrom records the copy.
+func (f *Fake) CopyFrom(ctx context.Context, container string, src string, dest string, archive bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := f.get("copy "+src+" from", container)
+	return err
+}
+
+// CopyArchiveTo reads the archive, and records the copy.
+func (f *Fake) CopyArchiveTo(ctx context.Context, r io.Reader, container string, destDir string, archive bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := f.get("copy archive to "+destDir, container)
+	return err
+}
+
+// CopyArchiveFrom writes an empty archive, and records the copy.
+func (f *Fake) CopyArchiveFrom(ctx context.Context, container string, src string, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	_, err := f.get("copy archive "+src+" from", container)
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	var empty bytes.Buffer
+	if err := tar.NewWriter(&empty).Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(empty.Bytes())
+	return err
+}
+
+// fakeCmder creates the commands of a Fake container.
+type fakeCmder struct {
+	fake      *Fake
+	ctx       context.Context
+	container string
+}
+
+func (c *fakeCmder) Command(name string, args ...string) exec.Cmd {
+	return &fakeCmd{fake: c.fake, ctx: c.ctx, container: c.container, command: append([]string{name}, args...)}
+}
+
+// fakeCmd is a command run in a Fake container.
+type fakeCmd struct {
+	fake      *Fake
+	ctx       context.Context
+	container string
+	command   []string
+	stdin     io.Reader
+	stdout    io.Writer
+}
+
+func (c *fakeCmd) Run() error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	return c.fake.run(c.container, c.command, c.stdin, c.stdout)
+}
+
+func (c *fakeCmd) SetEnv(...string) {}
+
+func (c *fakeCmd) SetStdin(r io.Reader) {
+	c.stdin = r
+}
+
+func (c *fakeCmd) SetStdout(w io.Writer) {
+	c.stdout = w
+}
+
+func (c *fakeCmd) SetStderr(io.Writer) {}
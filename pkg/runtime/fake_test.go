@@ -0,0 +1,137 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeCreate(t *testing.T) {
+	ctx := context.Background()
+	f := NewFake()
+	err := f.Create(ctx, "centos", []string{
+		"--name", "c-node0", "--hostname", "node0", "--label", "cluster=c",
+		"--privileged", "--mount", "type=bind,src=/a,dst=/b,readonly",
+		"-p", "22", "-p", "127.0.0.1:8080:80/udp", "--network", "net", "--network-alias", "node0.vind",
+	}, []string{"/sbin/init"})
+	assert.NoError(t, err)
+
+	c := f.Container("c-node0")
+	if assert.NotNil(t, c) {
+		assert.Equal(t, "node0", c.Hostname)
+		assert.Equal(t, "c", c.Labels["cluster"])
+		assert.True(t, c.Privileged)
+		assert.False(t, c.Running)
+		assert.Equal(t, "/b", c.Mounts[0].Destination)
+		assert.False(t, c.Mounts[0].RW)
+		assert.Equal(t, "32768", c.Ports["22/tcp"][0].HostPort)
+		assert.Equal(t, nat.PortBinding{HostIP: "127.0.0.1", HostPort: "8080"}, c.Ports["80/udp"][0])
+		assert.Equal(t, "172.17.0.2", c.Networks["net"].IPAddress)
+		assert.Equal(t, []string{"node0.vind"}, c.Networks["net"].Aliases)
+	}
+
+	assert.Error(t, f.Create(ctx, "centos", []string{"--name", "c-node0"}, nil))
+}
+
+func TestFakeLifecycle(t *testing.T) {
+	ctx := context.Background()
+	f := NewFake()
+	assert.NoError(t, f.Create(ctx, "centos", []string{"--name", "c-node0", "--label", "cluster=c", "-p", "22"}, nil))
+
+	details, err := f.Inspect(ctx, "c-node0")
+	assert.NoError(t, err)
+	assert.False(t, details.State.Running)
+	assert.Empty(t, details.NetworkSettings.Ports)
+	assert.Empty(t, details.NetworkSettings.Networks["bridge"].IPAddress)
+	assert.Error(t, f.Cmder(ctx, "c-node0").Command("true").Run())
+	containers, err := f.List(ctx, "cluster=c")
+	assert.NoError(t, err)
+	assert.Empty(t, containers)
+
+	var ran []string
+	f.OnExec = func(container string, command []string, stdin []byte) ([]byte, error) {
+		ran = append(ran, container+" "+command[0])
+		return []byte("hello\n"), nil
+	}
+	assert.NoError(t, f.Start(ctx, "c-node0"))
+	details, err = f.Inspect(ctx, "c-node0")
+	assert.NoError(t, err)
+	assert.True(t, details.State.Running)
+	assert.Equal(t, "32768", details.NetworkSettings.Ports["22/tcp"][0].HostPort)
+	assert.Equal(t, "172.17.0.2", details.NetworkSettings.Networks["bridge"].IPAddress)
+	lines, err := exec.CombinedOutputLines(f.Cmder(ctx, "c-node0").Command("echo", "hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, lines)
+	assert.Equal(t, []string{"c-node0 echo"}, ran)
+	containers, err = f.List(ctx, "cluster=c")
+	assert.NoError(t, err)
+	assert.Len(t, containers, 1)
+	containers, err = f.List(ctx, "cluster=other")
+	assert.NoError(t, err)
+	assert.Empty(t, containers)
+
+	f.Container("c-node0").Logs = "booted\n"
+	var logs bytes.Buffer
+	assert.NoError(t, f.Logs(ctx, "c-node0", &logs))
+	assert.Equal(t, "booted\n", logs.String())
+
+	assert.NoError(t, f.Stop(ctx, "c-node0"))
+	assert.NoError(t, f.Remove(ctx, "c-node0"))
+	assert.Nil(t, f.Container("c-node0"))
+	_, err = f.Inspect(ctx, "c-node0")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Equal(t, []string{"create c-node0", "exec c-node0", "start c-node0", "exec c-node0", "logs c-node0", "stop c-node0", "remove c-node0"}, f.Ops())
+	assert.True(t, errors.Is(f.Logs(ctx, "c-node0", &logs), ErrNotFound))
+}
+
+func TestFakeEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	f := NewFake()
+	events, errs := f.Events(ctx, "cluster=c")
+	others, _ := f.Events(ctx, "cluster=other")
+
+	assert.NoError(t, f.Create(ctx, "image", []string{"--name", "c-node0", "--label", "cluster=c"}, nil))
+	assert.NoError(t, f.Start(ctx, "c-node0"))
+	assert.NoError(t, f.Emit("c-node0", "oom", nil))
+	assert.NoError(t, f.Stop(ctx, "c-node0"))
+	assert.True(t, errors.Is(f.Emit("c-node1", "oom", nil), ErrNotFound))
+
+	var actions []string
+	for len(actions) < 5 {
+		event := <-events
+		assert.Equal(t, "c-node0", event.Container)
+		assert.Equal(t, "c", event.Attributes["cluster"])
+		actions = append(actions, event.Action)
+		if event.Action == "die" {
+			assert.Equal(t, "0", event.Attributes["exitCode"])
+		}
+	}
+	assert.Equal(t, []string{"create", "start", "oom", "die", "stop"}, actions)
+
+	cancel()
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.NoError(t, <-errs)
+	_, ok = <-others
+	assert.False(t, ok)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Package runtime abstracts the container runtime running the machines, so
+// that the clusters can be driven by Docker, or by an in-memory fake in
+// tests.
+package runtime
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/brightzheng100/vind/pkg/docker"
+	"github.com/brightzheng100/vind/pkg/exec"
+	"github.com/docker/docker/api/types"
+)
+
+// ErrNotFound is returned for a container which doesn't exist. It's wrapped
+// with fmt.Errorf and "%w", for errors.Is.
+var ErrNotFound = errors.New("container not found")
+
+// Runtime is the container runtime running the machines. Its operations, and
+// the commands it returns, are stopped when their context is done.
+type Runtime interface {
+	// IsRunning checks that the runtime can be used.
+	IsRunning(ctx context.Context) error
+	// PullIfNotPresent pulls the image if it's not present yet.
+	PullIfNotPresent(ctx context.Context, image string) error
+
+	// Create creates a container of the image, like "docker create" with the
+	// run arguments and the command.
+	Create(ctx context.Context, image string, runArgs []string, command []string) error
+	// ConnectNetwork connects the container to the network, with the aliases
+	// if any.
+	ConnectNetwork(ctx context.Context, container string, network string, aliases ...string) error
+	// Start starts the container.
+	Start(ctx context.Context, container string) error
+	// Stop stops the container.
+	Stop(ctx context.Context, container string) error
+	// Remove kills the container if it's running, and removes it with its
+	// volumes.
+	Remove(ctx context.Context, container string) error
+
+	// Inspect returns the details of the container, or ErrNotFound.
+	Inspect(ctx context.Context, container string) (*types.ContainerJSON, error)
+	// List returns the running containers having all the labels, like
+	// "creator=vind".
+	List(ctx context.Context, labels ...string) ([]docker.ContainerSummary, error)
+	// Events streams the events of the containers having all the labels,
+	// until the context is done. The error channel gets the error ending the
+	// stream early, if any, then both channels are closed.
+	Events(ctx context.Context, labels ...string) (<-chan docker.ContainerEvent, <-chan error)
+	// Logs writes the logs of the container, its output and error output, to
+	// w.
+	Logs(ctx context.Context, container string, w io.Writer) error
+
+	// Cmder returns the commands run as root in the container, through a tty
+	// when their output is read and their input is a terminal or nothing.
+	Cmder(ctx context.Context, container string) exec.Cmder
+	// Exec returns a command run in the container as the user, or root if
+	// empty, without a tty.
+	Exec(ctx context.Context, container string, user string, name string, args ...string) exec.Cmd
+	// Session returns an interactive command run in the container as the user,
+	// through a tty if its input is a terminal.
+	Session(ctx context.Context, container string, user string, name string, args ...string) exec.Cmd
+
+	// CopyTo copies a host file or directory into the container.
+	CopyTo(ctx context.Context, src string, container string, dest string, archive bool) error
+	// CopyFrom copies a file or directory of the container to the host.
+	CopyFrom(ctx context.Context, container string, src string, dest string, archive bool) error
+	// CopyArchiveTo extracts a tar archive into a directory of the container.
+	CopyArchiveTo(ctx context.Context, r io.Reader, container string, destDir string, archive bool) error
+	// CopyArchiveFrom writes a file or directory of the container as a tar
+	// archive.
+	CopyArchiveFrom(ctx context.Context, container string, src string, w io.Writer) error
+}
@@ -16,28 +16,77 @@ limitations under the License.
 package utils
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/sirupsen/logrus"
 )
 
+// LogLevelEnv is the environment variable giving the default log level.
+const LogLevelEnv = "LOG_LEVEL"
+
+// The structured fields of the log entries.
+const (
+	FieldCluster    = "cluster"
+	FieldMachineSet = "machineSet"
+	FieldMachine    = "machine"
+	FieldContainer  = "container"
+	FieldOperation  = "operation"
+	// FieldDuration is how long the operation took, in seconds.
+	FieldDuration = "duration"
+)
+
+// Logger logs to the standard error, the standard output being kept for the
+// output of the commands, like "vind show -o json".
 var Logger = logrus.New()
 
 func init() {
+	Logger.SetOutput(os.Stderr)
 	Logger.SetFormatter(&logrus.TextFormatter{})
 
 	// defaults to Info log level
 	Logger.SetLevel(logrus.InfoLevel)
 
 	// and log level is configurable by env vaiable $LOG_LEVEL
-	config_log_level := os.Getenv("LOG_LEVEL")
+	config_log_level := os.Getenv(LogLevelEnv)
 	if config_log_level != "" {
 		log_level, err := logrus.ParseLevel(config_log_level)
 		if err != nil {
 			Logger.Warnf("configured LOG_LEVEL is unparsable: %s, ignore and fall back to Info level", config_log_level)
 		} else {
-			Logger.Infof("log level is set to [%s]", log_level)
+			Logger.Debugf("log level is set to [%s]", log_level)
 			Logger.SetLevel(log_level)
 		}
 	}
 }
+
+// DefaultLogLevel returns the log level given by $LOG_LEVEL, info by default
+// or if it's unparsable, which init warns about.
+func DefaultLogLevel() string {
+	if level, err := logrus.ParseLevel(os.Getenv(LogLevelEnv)); err == nil {
+		return level.String()
+	}
+	return logrus.InfoLevel.String()
+}
+
+// ConfigureLogger sets the level of the Logger, like "debug", and its format,
+// "text" or "json". Quiet only logs the errors, whatever the level.
+func ConfigureLogger(level string, format string, quiet bool) error {
+	logLevel, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: %v", level, err)
+	}
+	if quiet {
+		logLevel = logrus.ErrorLevel
+	}
+	switch format {
+	case "text":
+		Logger.SetFormatter(&logrus.TextFormatter{})
+	case "json":
+		Logger.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return fmt.Errorf("invalid log format %q: should be text or json", format)
+	}
+	Logger.SetLevel(logLevel)
+	return nil
+}
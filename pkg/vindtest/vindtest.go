@@ -0,0 +1,202 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Package vindtest spins throwaway vind clusters up in Go tests.
+//
+//	func TestReplication(t *testing.T) {
+//		cluster := vindtest.NewCluster(t, config.Config{MachineSets: ...})
+//		out := cluster.Exec("db-node0", "hostname")
+//		...
+//	}
+package vindtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/brightzheng100/vind/pkg/runtime"
+)
+
+// defaultPrivateKey is the name of the cluster SSH key when the configuration
+// has none. It's created in the cluster directory, removed with the cluster.
+const defaultPrivateKey = "cluster-key"
+
+// maxNameLength keeps the container names, made of the cluster, MachineSet
+// and machine names, readable.
+const maxNameLength = 40
+
+// Cluster is a cluster created for a test, deleted when it completes.
+type Cluster struct {
+	*cluster.Cluster
+	t testing.TB
+}
+
+// NewCluster creates the cluster of the configuration for the test, with a
+// unique name derived from the test name, and deletes it when the test and
+// its subtests complete. The cluster SSH key defaults to one created for the
+// cluster. The logs of the machines are dumped into the test log if the test
+// fails. The test is skipped if Docker isn't running.
+func NewCluster(t testing.TB, cfg config.Config) *Cluster {
+	t.Helper()
+	return newCluster(t, cfg, runtime.Docker{})
+}
+
+// newCluster creates the cluster of the configuration for the test on the
+// runtime.
+func newCluster(t testing.TB, cfg config.Config, rt runtime.Runtime) *Cluster {
+	t.Helper()
+	if err := rt.IsRunning(context.Background()); err != nil {
+		t.Skipf("vindtest: the container runtime is not running: %v", err)
+	}
+
+	cfg.Cluster.Name = uniqueName(t.Name())
+	if cfg.Cluster.PrivateKey == "" {
+		cfg.Cluster.PrivateKey = defaultPrivateKey
+	}
+	c, err := cluster.New(cfg)
+	if err != nil {
+		t.Fatalf("vindtest: invalid cluster configuration: %v", err)
+	}
+	tc := &Cluster{Cluster: c.SetRuntime(rt), t: t}
+
+	// registered first, to clean up the machines of a partial creation too
+	t.Cleanup(tc.cleanup)
+	if err := c.Create(context.Background(), cluster.CreateOptions{}); err != nil {
+		t.Fatalf("vindtest: can't create cluster %s: %v", c.Name(), err)
+	}
+	return tc
+}
+
+// uniqueName returns a cluster name made of the test name and a random
+// suffix.
+func uniqueName(testName string) string {
+	name := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(testName), "-"), "-")
+	if len(name) > maxNameLength-7 {
+		name = strings.TrimRight(name[:maxNameLength-7], "-")
+	}
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	if name == "" {
+		return "vindtest-" + hex.EncodeToString(suffix)
+	}
+	return name + "-" + hex.EncodeToString(suffix)
+}
+
+// invalidNameChars matches what can't be in container names.
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// cleanup dumps the logs of the machines if the test failed, and deletes the
+// cluster with its files.
+func (c *Cluster) cleanup() {
+	if c.t.Failed() {
+		c.DumpLogs()
+	}
+	if err := c.Delete(context.Background()); err != nil {
+		c.t.Errorf("vindtest: can't delete cluster %s: %v", c.Name(), err)
+	}
+	if err := os.RemoveAll(c.Dir()); err != nil {
+		c.t.Errorf("vindtest: can't remove the files of cluster %s: %v", c.Name(), err)
+	}
+}
+
+// Machine returns the machine, failing the test if it doesn't exist.
+func (c *Cluster) Machine(machineName string) *cluster.Machine {
+	c.t.Helper()
+	m, err := c.GetMachineByMachineName(machineName)
+	if err != nil {
+		c.t.Fatalf("vindtest: %v", err)
+	}
+	return m
+}
+
+// Exec runs the command in the machine, as root, and returns its output
+// without the trailing newline. The test fails if the command does.
+func (c *Cluster) Exec(machineName string, command ...string) string {
+	c.t.Helper()
+	stdout, stderr, err := c.ExecE(machineName, command...)
+	if err != nil {
+		c.t.Fatalf("vindtest: %s: %v failed: %v\n%s", machineName, command, err, stderr)
+	}
+	return stdout
+}
+
+// ExecE runs the command in the machine, as root, and returns its output and
+// error output, without the trailing newlines, and its error.
+func (c *Cluster) ExecE(machineName string, command ...string) (string, string, error) {
+	c.t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := c.Cluster.Exec(context.Background(), c.Machine(machineName), command, cluster.ExecOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	return strings.TrimRight(stdout.String(), "\n"), strings.TrimRight(stderr.String(), "\n"), err
+}
+
+// ReadFile returns the content of a file of the machine, failing the test if
+// it can't be read.
+func (c *Cluster) ReadFile(machineName string, path string) []byte {
+	c.t.Helper()
+	var content, stderr bytes.Buffer
+	err := c.Cluster.Exec(context.Background(), c.Machine(machineName), []string{"cat", path}, cluster.ExecOptions{
+		Stdout: &content,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		c.t.Fatalf("vindtest: %s: can't read %s: %v\n%s", machineName, path, err, stderr.String())
+	}
+	return content.Bytes()
+}
+
+// WriteFile writes a file of the machine, failing the test if it can't be
+// written.
+func (c *Cluster) WriteFile(machineName string, path string, content []byte) {
+	c.t.Helper()
+	var stderr bytes.Buffer
+	err := c.Cluster.Exec(context.Background(), c.Machine(machineName), []string{"/bin/sh", "-c", `cat > "$1"`, "sh", path}, cluster.ExecOptions{
+		Stdin:  bytes.NewReader(content),
+		Stderr: &stderr,
+	})
+	if err != nil {
+		c.t.Fatalf("vindtest: %s: can't write %s: %v\n%s", machineName, path, err, stderr.String())
+	}
+}
+
+// DumpLogs writes the container logs of the created machines into the test
+// log.
+func (c *Cluster) DumpLogs() {
+	machines, err := c.GetMachinesInSet("")
+	if err != nil {
+		c.t.Logf("vindtest: %v", err)
+		return
+	}
+	for _, m := range machines {
+		if !m.IsCreated() {
+			continue
+		}
+		var logs bytes.Buffer
+		if err := c.Runtime().Logs(context.Background(), m.ContainerName(), &logs); err != nil {
+			c.t.Logf("vindtest: can't get the logs of machine %s: %v", m.MachineName(), err)
+		}
+		c.t.Logf("vindtest: logs of machine %s:\n%s", m.MachineName(), logs.String())
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright © 2024-2025 Bright Zheng <bright.zheng@outlook.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package vindtest
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/brightzheng100/vind/pkg/cluster"
+	"github.com/brightzheng100/vind/pkg/config"
+	"github.com/brightzheng100/vind/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUniqueName(t *testing.T) {
+	valid := regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+	name := uniqueName("TestReplication/Three_Nodes")
+	assert.Regexp(t, `^testreplication-three-nodes-[0-9a-f]{6}$`, name)
+	assert.NotEqual(t, name, uniqueName("TestReplication/Three_Nodes"))
+
+	name = uniqueName("TestAVeryLongTestNameWhichWouldMakeLongContainerNames")
+	assert.True(t, len(name) <= maxNameLength)
+	assert.Regexp(t, valid, name)
+
+	assert.Regexp(t, `^vindtest-[0-9a-f]{6}$`, uniqueName("/_"))
+}
+
+func TestNewClusterDefaultKey(t *testing.T) {
+	t.Setenv(cluster.HomeEnv, t.TempDir())
+	fake := runtime.NewFake()
+	fake.OnExec = func(container string, command []string, stdin []byte) ([]byte, error) {
+		if len(command) == 3 && command[2] == cluster.HOST_KEYS_SCRIPT {
+			return []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBFh1cJvuf8ycWpbN1LGBRm8TAM2/ZGUGtjhU7tH0PPf\n"), nil
+		}
+		return nil, nil
+	}
+	cfg := config.Config{MachineSets: []config.MachineSet{{
+		Name:     "db",
+		Replicas: 1,
+		Spec:     config.Machine{Image: "quay.io/brightzheng100/ubuntu22.04", Name: "node%d"},
+	}}}
+
+	var dir string
+	t.Run("create", func(t *testing.T) {
+		c := newCluster(t, cfg, fake)
+		dir = c.Dir()
+		key, _, err := c.ClusterKey()
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, defaultPrivateKey), key)
+		assert.FileExists(t, key)
+	})
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err), "%v", err)
+	assert.Empty(t, fake.Containers())
+}